@@ -0,0 +1,38 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// alignTimestamps rounds the timestamps of all samples down to the nearest multiple of
+// the interval, like Prometheus aligns scrapes. Replicas that push the same series then
+// send identical timestamps, which makes deduplication and queries consistent. Nothing is
+// changed if the interval is shorter than a millisecond.
+func alignTimestamps(timeSeries []*prompb.TimeSeries, interval time.Duration) {
+	intervalMillis := int64(interval / time.Millisecond)
+	if intervalMillis <= 0 {
+		return
+	}
+
+	for _, ts := range timeSeries {
+		for i := range ts.Samples {
+			ts.Samples[i].Timestamp -= ts.Samples[i].Timestamp % intervalMillis
+		}
+	}
+}
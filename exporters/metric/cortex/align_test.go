@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestAlignTimestamps checks whether sample timestamps are rounded down to the nearest
+// multiple of the interval.
+func TestAlignTimestamps(t *testing.T) {
+	tests := []struct {
+		testName  string
+		interval  time.Duration
+		timestamp int64
+		want      int64
+	}{
+		{"Timestamp on boundary", 10 * time.Second, 1600000000000, 1600000000000},
+		{"Timestamp within interval", 10 * time.Second, 1600000009999, 1600000000000},
+		{"Timestamp after boundary", 15 * time.Second, 1600000001000, 1599999990000},
+		{"No interval", 0, 1600000001234, 1600000001234},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			timeSeries := []*prompb.TimeSeries{
+				{
+					Labels:  []*prompb.Label{{Name: "__name__", Value: "metric_name"}},
+					Samples: []prompb.Sample{{Value: 1, Timestamp: test.timestamp}},
+				},
+			}
+			alignTimestamps(timeSeries, test.interval)
+			require.Equal(t, test.want, timeSeries[0].Samples[0].Timestamp)
+		})
+	}
+}
+
+// TestAlignTimestampsExport checks whether exported samples are aligned to PushInterval
+// when AlignTimestamps is set.
+func TestAlignTimestampsExport(t *testing.T) {
+	var timestamps []int64
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		for _, ts := range writeRequest.Timeseries {
+			for _, sample := range ts.Samples {
+				timestamps = append(timestamps, sample.Timestamp)
+			}
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:        server.URL,
+			PushInterval:    10 * time.Second,
+			AlignTimestamps: true,
+		},
+	}
+
+	desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+	end := time.Unix(1600000007, 123*int64(time.Millisecond))
+	checkpointSet := &recordCheckpointSet{
+		records: []export.Record{newSumRecord(t, &desc, 1, end.Add(-time.Minute), end)},
+	}
+	require.Nil(t, exporter.Export(context.Background(), checkpointSet))
+	require.Equal(t, []int64{1600000000000}, timestamps)
+}
@@ -15,13 +15,17 @@
 package cortex
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -36,6 +40,33 @@ var (
 	// ErrFailedToReadFile occurs when a password / bearer token file exists, but could
 	// not be read.
 	ErrFailedToReadFile = fmt.Errorf("Failed to read password / bearer token file")
+
+	// ErrInvalidRenegotiation occurs when the TLS renegotiation setting is not one of
+	// "never", "once", or "freely".
+	ErrInvalidRenegotiation = fmt.Errorf("TLS renegotiation must be one of never, once, or freely")
+
+	// ErrIncompleteClientCertificate occurs when only one of the client certificate file
+	// and its key file was provided.
+	ErrIncompleteClientCertificate = fmt.Errorf("Client certificate requires both cert_file and key_file")
+
+	// ErrServerNameWithInsecureSkipVerify occurs when a server name to verify the server
+	// certificate against is provided while verification is disabled.
+	ErrServerNameWithInsecureSkipVerify = fmt.Errorf("TLS server_name has no effect when insecure_skip_verify is set")
+
+	// ErrConflictingCA occurs when both an inline CA certificate and a CA file were
+	// provided.
+	ErrConflictingCA = fmt.Errorf("TLS ca and ca_file cannot both be set")
+
+	// ErrInvalidCA occurs when the inline CA certificate does not contain a PEM-encoded
+	// certificate.
+	ErrInvalidCA = fmt.Errorf("TLS ca does not contain a PEM certificate")
+)
+
+// Default timeouts for establishing connections, which match the ones of
+// http.DefaultTransport.
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
 )
 
 // addBasicAuth sets the Authorization header for basic authentication using a username
@@ -65,7 +96,8 @@ func (e *Exporter) addBasicAuth(req *http.Request) error {
 		if err != nil {
 			return ErrFailedToReadFile
 		}
-		password := string(file)
+		// Files usually end with a newline that is not part of the password.
+		password := strings.TrimSpace(string(file))
 		req.SetBasicAuth(username, password)
 		return nil
 	}
@@ -96,7 +128,8 @@ func (e *Exporter) addBearerTokenAuth(req *http.Request) error {
 		if err != nil {
 			return ErrFailedToReadFile
 		}
-		bearerTokenString := "Bearer " + string(file)
+		// Files usually end with a newline that is not part of the token.
+		bearerTokenString := "Bearer " + strings.TrimSpace(string(file))
 		req.Header.Set("Authorization", bearerTokenString)
 		return nil
 	}
@@ -120,7 +153,38 @@ func (e *Exporter) buildClient() (*http.Client, error) {
 	}
 
 	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   timeoutOrDefault(e.config.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: e.config.ResponseHeaderTimeout,
+	}
+
+	// Connections are kept alive and reused across pushes unless disabled.
+	if e.config.ReuseConnections != nil && !*e.config.ReuseConnections {
+		transport.DisableKeepAlives = true
+	}
+
+	// Wait at most a second for the server to confirm an Expect: 100-continue request
+	// before sending the body anyway.
+	if e.config.Use100Continue {
+		transport.ExpectContinueTimeout = time.Second
+	}
+
+	// Connect with the user-supplied dialer if there is one. DialTimeout does not apply
+	// to it.
+	dial := (&net.Dialer{
+		Timeout: timeoutOrDefault(e.config.DialTimeout, defaultDialTimeout),
+	}).DialContext
+	if e.config.DialContext != nil {
+		dial = e.config.DialContext
+	}
+	transport.DialContext = dial
+
+	// Dial the socket instead of the host in the request URL for Unix domain socket
+	// endpoints.
+	if socket, _, ok := parseUnixEndpoint(e.config.Endpoint); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, "unix", socket)
+		}
 	}
 
 	// Convert proxy url to proxy function for use in the created Transport.
@@ -139,9 +203,60 @@ func (e *Exporter) buildClient() (*http.Client, error) {
 		Timeout:   e.config.RemoteTimeout,
 	}
 
+	// Redirects are not followed by default since they could send the Authorization
+	// header to an unexpected host. The redirect response is returned instead, which
+	// fails the request.
+	if !e.config.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
 	return &client, nil
 }
 
+// timeoutOrDefault returns the timeout if it is set and the default otherwise.
+func timeoutOrDefault(timeout, def time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return def
+}
+
+// parseUnixEndpoint splits an endpoint of the form unix://<socket path>[:<request path>]
+// into the path of the socket and the path of the request, which defaults to "/". It
+// returns false if the endpoint is not a Unix domain socket endpoint.
+func parseUnixEndpoint(endpoint string) (string, string, bool) {
+	if !strings.HasPrefix(endpoint, "unix://") {
+		return "", "", false
+	}
+
+	socket := strings.TrimPrefix(endpoint, "unix://")
+	if i := strings.Index(socket, ":"); i >= 0 {
+		return socket[:i], socket[i+1:], true
+	}
+	return socket, "/", true
+}
+
+// validateTLSConfig checks the TLSConfig map for settings that cannot be used together, so
+// that they are rejected with the Config instead of when the first request is sent.
+func validateTLSConfig(tlsConfig map[string]string) error {
+	// A server name is set to verify the server certificate against it, which never
+	// happens when verification is disabled.
+	if tlsConfig["server_name"] != "" {
+		if skip, err := strconv.ParseBool(tlsConfig["insecure_skip_verify"]); err == nil && skip {
+			return ErrServerNameWithInsecureSkipVerify
+		}
+	}
+	if tlsConfig["ca"] != "" && tlsConfig["ca_file"] != "" {
+		return ErrConflictingCA
+	}
+	if (tlsConfig["cert_file"] == "") != (tlsConfig["key_file"] == "") {
+		return ErrIncompleteClientCertificate
+	}
+	return nil
+}
+
 // buildTLSConfig uses the TLSConfig map in Config to create a tls.Config struct.
 func (e *Exporter) buildTLSConfig() (*tls.Config, error) {
 	tlsConfig := &tls.Config{}
@@ -161,6 +276,11 @@ func (e *Exporter) buildTLSConfig() (*tls.Config, error) {
 	}
 	tlsConfig.InsecureSkipVerify = parsedBool
 
+	// Configure TLS session resumption and renegotiation if they are set.
+	if err := e.setSessionOptions(tlsConfig); err != nil {
+		return nil, err
+	}
+
 	// Load certificates from CA file if it exists.
 	if err := e.loadCACertificates(tlsConfig); err != nil {
 		return nil, err
@@ -174,11 +294,50 @@ func (e *Exporter) buildTLSConfig() (*tls.Config, error) {
 	return tlsConfig, nil
 }
 
-// loadCACertificates reads a CA file and updates the certificate pool in a tls Config
-// struct.
+// setSessionOptions configures TLS session ticket resumption and renegotiation in a tls
+// Config struct. With session tickets, the client caches sessions so that connections
+// opened after the first one can skip the full handshake.
+func (e *Exporter) setSessionOptions(tlsConfig *tls.Config) error {
+	if sessionTickets := e.config.TLSConfig["session_tickets"]; sessionTickets != "" {
+		// Viper reads the bool as a string since it is in a map.
+		enabled, err := strconv.ParseBool(sessionTickets)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		} else {
+			tlsConfig.SessionTicketsDisabled = true
+		}
+	}
+
+	switch e.config.TLSConfig["renegotiation"] {
+	case "", "never":
+		tlsConfig.Renegotiation = tls.RenegotiateNever
+	case "once":
+		tlsConfig.Renegotiation = tls.RenegotiateOnceAsClient
+	case "freely":
+		tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+	default:
+		return ErrInvalidRenegotiation
+	}
+
+	return nil
+}
+
+// loadCACertificates reads a CA file or an inline PEM-encoded CA certificate and updates
+// the certificate pool in a tls Config struct.
 func (e *Exporter) loadCACertificates(tlsConfig *tls.Config) error {
 	caFile := e.config.TLSConfig["ca_file"]
+	ca := e.config.TLSConfig["ca"]
 
+	if ca != "" {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(ca)) {
+			return ErrInvalidCA
+		}
+		tlsConfig.RootCAs = certPool
+	}
 	if caFile != "" {
 		caFileData, err := ioutil.ReadFile(caFile)
 		if err != nil {
@@ -197,12 +356,14 @@ func (e *Exporter) loadClientCertificate(tlsConfig *tls.Config) error {
 	certFile := e.config.TLSConfig["cert_file"]
 	keyFile := e.config.TLSConfig["key_file"]
 
-	if certFile != "" && keyFile != "" {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			return err
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
+	if certFile == "" || keyFile == "" {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
 	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
 	return nil
 }
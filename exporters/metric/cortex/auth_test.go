@@ -15,6 +15,7 @@
 package cortex
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -22,6 +23,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net"
@@ -96,6 +98,18 @@ func TestAuthentication(t *testing.T) {
 			),
 			expectedError: nil,
 		},
+		{
+			testName: "Basic Auth with password file with trailing newline",
+			basicAuth: map[string]string{
+				"username":      "TestUser",
+				"password_file": "passwordFile",
+			},
+			basicAuthPasswordFileContents: []byte("TestPassword\n"),
+			expectedAuthHeaderValue: "Basic " + base64.StdEncoding.EncodeToString(
+				[]byte("TestUser:TestPassword"),
+			),
+			expectedError: nil,
+		},
 		{
 			testName: "Basic Auth with bad password file",
 			basicAuth: map[string]string{
@@ -124,6 +138,13 @@ func TestAuthentication(t *testing.T) {
 			bearerTokenFileContents: []byte("testToken"),
 			expectedError:           nil,
 		},
+		{
+			testName:                "Bearer Token with bearer token file with trailing newline",
+			bearerTokenFile:         "bearerTokenFile",
+			expectedAuthHeaderValue: "Bearer testToken",
+			bearerTokenFileContents: []byte("testToken\r\n"),
+			expectedError:           nil,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.testName, func(t *testing.T) {
@@ -157,7 +178,7 @@ func TestAuthentication(t *testing.T) {
 			// Create a HTTP request and add headers to it through an Exporter. Since the
 			// Exporter has an empty Headers map, authentication methods will be called.
 			exporter := Exporter{
-				Config{
+				config: Config{
 					BasicAuth:       test.basicAuth,
 					BearerToken:     test.bearerToken,
 					BearerTokenFile: test.bearerTokenFile,
@@ -264,6 +285,60 @@ func TestBuildClient(t *testing.T) {
 	}
 }
 
+// TestInlineCA checks whether the server is verified with a CA certificate provided
+// inline in TLSConfig and whether invalid or conflicting CA settings are rejected.
+func TestInlineCA(t *testing.T) {
+	handler := func(rw http.ResponseWriter, req *http.Request) {}
+	server := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	caCertPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.TLS.Certificates[0].Certificate[0],
+	})
+
+	tests := []struct {
+		testName      string
+		tlsConfig     map[string]string
+		expectedError error
+	}{
+		{
+			testName: "Inline CA",
+			tlsConfig: map[string]string{
+				"ca":                   string(caCertPEM),
+				"insecure_skip_verify": "0",
+			},
+		},
+		{
+			testName: "Inline CA without a certificate",
+			tlsConfig: map[string]string{
+				"ca":                   "not a certificate",
+				"insecure_skip_verify": "0",
+			},
+			expectedError: ErrInvalidCA,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{
+				config: Config{
+					TLSConfig: test.tlsConfig,
+				},
+			}
+			client, err := exporter.buildClient()
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.Nil(t, err)
+
+			_, err = client.Get(server.URL)
+			require.Nil(t, err)
+		})
+	}
+}
+
 // TestMutualTLS is an integration test that checks whether the Exporter's client can
 // successfully verify a server and send a HTTP request and whether a server can
 // successfully verify the Exporter client and receive the HTTP request.
@@ -317,7 +392,7 @@ func TestMutualTLS(t *testing.T) {
 
 	// Create an Exporter client with the client and CA certificate files.
 	exporter := Exporter{
-		Config{
+		config: Config{
 			TLSConfig: map[string]string{
 				"ca_file":              "./ca_cert.pem",
 				"cert_file":            "./client_cert.pem",
@@ -517,3 +592,314 @@ func generateServerTLSConfig(caCertFile string, servingCertFile string, servingK
 	}
 	return tlsConfig, nil
 }
+
+// TestFollowRedirects checks whether the client built by the Exporter only follows
+// redirects when FollowRedirects is set.
+func TestFollowRedirects(t *testing.T) {
+	tests := []struct {
+		testName        string
+		followRedirects bool
+		expectedError   error
+		wantReceived    bool
+	}{
+		{
+			testName:        "Redirects are rejected",
+			followRedirects: false,
+			expectedError:   fmt.Errorf("%v", "307 Temporary Redirect"),
+			wantReceived:    false,
+		},
+		{
+			testName:        "Redirects are followed",
+			followRedirects: true,
+			expectedError:   nil,
+			wantReceived:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			// Set up a server that records whether it received a request and a server
+			// that redirects to it.
+			received := false
+			target := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				received = true
+			}))
+			defer target.Close()
+			redirect := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				http.Redirect(rw, req, target.URL, http.StatusTemporaryRedirect)
+			}))
+			defer redirect.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:        redirect.URL,
+					FollowRedirects: test.followRedirects,
+				},
+			}
+			err := exporter.send(context.Background(), []byte("message"), &ExportResult{})
+			require.Equal(t, test.expectedError, err)
+			require.Equal(t, test.wantReceived, received)
+		})
+	}
+}
+
+// TestResponseHeaderTimeout checks whether a request fails after ResponseHeaderTimeout
+// when the server receives the request but never responds.
+func TestResponseHeaderTimeout(t *testing.T) {
+	// Set up a server that reads the request body and then hangs until the test ends.
+	done := make(chan struct{})
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		ioutil.ReadAll(req.Body)
+		<-done
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	defer close(done)
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:              server.URL,
+			RemoteTimeout:         30 * time.Second,
+			ResponseHeaderTimeout: 50 * time.Millisecond,
+		},
+	}
+
+	start := time.Now()
+	err := exporter.send(context.Background(), []byte("message"), &ExportResult{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout awaiting response headers")
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+// TestSessionOptions checks whether the TLS session options in TLSConfig are applied to
+// the tls.Config of the client.
+func TestSessionOptions(t *testing.T) {
+	tests := []struct {
+		testName              string
+		tlsConfig             map[string]string
+		wantSessionCache      bool
+		wantTicketsDisabled   bool
+		expectedRenegotiation tls.RenegotiationSupport
+		expectedError         error
+	}{
+		{
+			testName: "Session tickets enabled",
+			tlsConfig: map[string]string{
+				"insecure_skip_verify": "false",
+				"session_tickets":      "true",
+			},
+			wantSessionCache:      true,
+			expectedRenegotiation: tls.RenegotiateNever,
+		},
+		{
+			testName: "Session tickets disabled",
+			tlsConfig: map[string]string{
+				"insecure_skip_verify": "false",
+				"session_tickets":      "false",
+			},
+			wantTicketsDisabled:   true,
+			expectedRenegotiation: tls.RenegotiateNever,
+		},
+		{
+			testName: "Renegotiation once",
+			tlsConfig: map[string]string{
+				"insecure_skip_verify": "false",
+				"renegotiation":        "once",
+			},
+			expectedRenegotiation: tls.RenegotiateOnceAsClient,
+		},
+		{
+			testName: "Invalid renegotiation",
+			tlsConfig: map[string]string{
+				"insecure_skip_verify": "false",
+				"renegotiation":        "always",
+			},
+			expectedError: ErrInvalidRenegotiation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{
+				config: Config{
+					TLSConfig: test.tlsConfig,
+				},
+			}
+			client, err := exporter.buildClient()
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.Nil(t, err)
+
+			tlsConfig := client.Transport.(*http.Transport).TLSClientConfig
+			require.Equal(t, test.wantSessionCache, tlsConfig.ClientSessionCache != nil)
+			require.Equal(t, test.wantTicketsDisabled, tlsConfig.SessionTicketsDisabled)
+			require.Equal(t, test.expectedRenegotiation, tlsConfig.Renegotiation)
+		})
+	}
+}
+
+// TestUnixSocketEndpoint checks whether requests are sent to a Unix domain socket when
+// the endpoint has the unix:// scheme.
+func TestUnixSocketEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cortex")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := dir + "/cortex.sock"
+
+	// Set up a server that listens on the socket and records the request path.
+	var path string
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	listener, err := net.Listen("unix", socket)
+	require.Nil(t, err)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint: "unix://" + socket + ":/api/prom/push",
+		},
+	}
+	require.Nil(t, exporter.send(context.Background(), []byte("message"), &ExportResult{}))
+	require.Equal(t, "/api/prom/push", path)
+}
+
+// TestDialContext checks whether the client connects with the dialer in DialContext.
+func TestDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	var network, addr string
+	exporter := Exporter{
+		config: Config{
+			Endpoint: server.URL,
+			DialContext: func(ctx context.Context, n, a string) (net.Conn, error) {
+				network, addr = n, a
+				return (&net.Dialer{}).DialContext(ctx, n, a)
+			},
+		},
+	}
+	require.Nil(t, exporter.send(context.Background(), []byte("message"), &ExportResult{}))
+	require.Equal(t, "tcp", network)
+	require.Equal(t, strings.TrimPrefix(server.URL, "http://"), addr)
+}
+
+// TestTLSHandshakeTimeout checks whether a request fails after TLSHandshakeTimeout when
+// the server accepts the connection but never completes the handshake, and whether the
+// default timeout is used when it is not set.
+func TestTLSHandshakeTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:            "https://" + listener.Addr().String(),
+			RemoteTimeout:       30 * time.Second,
+			TLSHandshakeTimeout: 50 * time.Millisecond,
+		},
+	}
+
+	start := time.Now()
+	err = exporter.send(context.Background(), []byte("message"), &ExportResult{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "TLS handshake timeout")
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	exporter = Exporter{config: Config{Endpoint: "https://" + listener.Addr().String()}}
+	client, err := exporter.client()
+	require.Nil(t, err)
+	require.Equal(t, defaultTLSHandshakeTimeout, client.Transport.(*http.Transport).TLSHandshakeTimeout)
+}
+
+// TestTimeoutOrDefault checks whether unset timeouts fall back to their default.
+func TestTimeoutOrDefault(t *testing.T) {
+	require.Equal(t, defaultDialTimeout, timeoutOrDefault(0, defaultDialTimeout))
+	require.Equal(t, time.Second, timeoutOrDefault(time.Second, defaultDialTimeout))
+}
+
+// TestParseUnixEndpoint checks whether Unix domain socket endpoints are split into the
+// socket path and the request path.
+func TestParseUnixEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint   string
+		wantSocket string
+		wantPath   string
+		wantOK     bool
+	}{
+		{"unix:///var/run/cortex.sock", "/var/run/cortex.sock", "/", true},
+		{"unix:///var/run/cortex.sock:/api/prom/push", "/var/run/cortex.sock", "/api/prom/push", true},
+		{"http://localhost:9009/api/prom/push", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.endpoint, func(t *testing.T) {
+			socket, path, ok := parseUnixEndpoint(test.endpoint)
+			require.Equal(t, test.wantSocket, socket)
+			require.Equal(t, test.wantPath, path)
+			require.Equal(t, test.wantOK, ok)
+		})
+	}
+}
+
+// TestUse100Continue checks whether requests carry an Expect: 100-continue header and the
+// client waits for the server's confirmation when Use100Continue is set.
+func TestUse100Continue(t *testing.T) {
+	tests := []struct {
+		testName       string
+		use100Continue bool
+		wantExpect     string
+		wantTimeout    time.Duration
+	}{
+		{
+			testName:       "Enabled",
+			use100Continue: true,
+			wantExpect:     "100-continue",
+			wantTimeout:    time.Second,
+		},
+		{
+			testName:       "Disabled",
+			use100Continue: false,
+			wantExpect:     "",
+			wantTimeout:    0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var expect string
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				expect = req.Header.Get("Expect")
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:       server.URL,
+					Use100Continue: test.use100Continue,
+				},
+			}
+			require.Nil(t, exporter.send(context.Background(), []byte("message"), &ExportResult{}))
+			require.Equal(t, test.wantExpect, expect)
+
+			client, err := exporter.client()
+			require.Nil(t, err)
+			require.Equal(t, test.wantTimeout, client.Transport.(*http.Transport).ExpectContinueTimeout)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// BodyStrategyBuffer builds the compressed request body before a request is sent and
+	// keeps it until the request succeeded, so retries send it again without encoding the
+	// TimeSeries again. This is the default.
+	BodyStrategyBuffer = "buffer"
+
+	// BodyStrategyStream encodes the request body while the request is sent, with chunked
+	// transfer encoding, and does not keep it between attempts. Retries encode the
+	// TimeSeries again.
+	BodyStrategyStream = "stream"
+)
+
+// timeSeriesTag is the key of a TimeSeries in a marshaled WriteRequest, which is field 1
+// with the length-delimited wire type.
+const timeSeriesTag = 1<<3 | 2
+
+// snappyBlockSize is the size of the blocks Snappy compresses the input in. Compressing
+// every block on its own produces the same output as compressing the whole input at once.
+const snappyBlockSize = 64 * 1024
+
+// streamMessage returns a Reader of the compressed message of TimeSeries that is encoded
+// while it is read, one TimeSeries at a time, so that neither the marshaled nor the
+// compressed message is held in memory as a whole. The size of the compressed message is
+// stored in size once it is known. Closing the Reader before it was read to the end stops
+// the encoding.
+func (e *Exporter) streamMessage(timeSeries []*prompb.TimeSeries, size *int64) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		counter := &countingWriter{writer: writer}
+		err := e.encodeMessage(counter, timeSeries)
+		if err == nil {
+			atomic.StoreInt64(size, counter.written)
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader
+}
+
+// encodeMessage writes the message buildMessage creates to a Writer, marshaling and
+// compressing one TimeSeries at a time.
+func (e *Exporter) encodeMessage(writer io.Writer, timeSeries []*prompb.TimeSeries) error {
+	writeRequest := &prompb.WriteRequest{
+		Timeseries: timeSeries,
+	}
+	compressor := e.compressWriter(writer, writeRequest.Size())
+
+	var key [1 + binary.MaxVarintLen64]byte
+	key[0] = timeSeriesTag
+	for _, ts := range timeSeries {
+		message, err := ts.Marshal()
+		if err != nil {
+			return err
+		}
+		n := binary.PutUvarint(key[1:], uint64(len(message)))
+		if _, err := compressor.Write(key[:1+n]); err != nil {
+			return err
+		}
+		if _, err := compressor.Write(message); err != nil {
+			return err
+		}
+	}
+	return compressor.Close()
+}
+
+// compressWriter returns a Writer that compresses a marshaled WriteRequest of the given
+// size according to Compression and writes it to writer. Close has to be called to write
+// the end of the compressed message.
+func (e *Exporter) compressWriter(writer io.Writer, size int) io.WriteCloser {
+	switch e.config.Compression {
+	case CompressionGzip:
+		return gzip.NewWriter(writer)
+	case CompressionNone:
+		return nopWriteCloser{writer}
+	}
+	return newSnappyBlockWriter(writer, size)
+}
+
+// nopWriteCloser is a Writer with a Close method that does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// countingWriter is a Writer that counts the bytes written to it.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+// Write writes p and counts the bytes written.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// snappyBlockWriter compresses its input in the Snappy block format the remote write
+// protocol uses, like snappy.Encode, without holding more than one block of it. The
+// block format starts with the length of the input, which therefore has to be known up
+// front.
+type snappyBlockWriter struct {
+	writer  io.Writer
+	header  []byte
+	block   []byte
+	encoded []byte
+}
+
+// newSnappyBlockWriter returns a snappyBlockWriter for an input of the given size.
+func newSnappyBlockWriter(writer io.Writer, size int) *snappyBlockWriter {
+	header := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(header, uint64(size))
+	return &snappyBlockWriter{
+		writer:  writer,
+		header:  header[:n],
+		block:   make([]byte, 0, snappyBlockSize),
+		encoded: make([]byte, snappy.MaxEncodedLen(snappyBlockSize)),
+	}
+}
+
+// Write adds p to the current block and compresses every block that is full.
+func (s *snappyBlockWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := copy(s.block[len(s.block):cap(s.block)], p)
+		s.block = s.block[:len(s.block)+n]
+		p = p[n:]
+		if len(s.block) == cap(s.block) {
+			if err := s.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close compresses the last block.
+func (s *snappyBlockWriter) Close() error {
+	return s.flush()
+}
+
+// flush writes the header if it was not written yet and compresses the current block.
+func (s *snappyBlockWriter) flush() error {
+	if s.header != nil {
+		if _, err := s.writer.Write(s.header); err != nil {
+			return err
+		}
+		s.header = nil
+	}
+	if len(s.block) == 0 {
+		return nil
+	}
+
+	// snappy.Encode prefixes the compressed block with its own length, which is
+	// replaced by the length of the whole input written in the header.
+	encoded := snappy.Encode(s.encoded, s.block)
+	_, n := binary.Uvarint(encoded)
+	s.block = s.block[:0]
+	_, err := s.writer.Write(encoded[n:])
+	return err
+}
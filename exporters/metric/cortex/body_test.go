@@ -0,0 +1,192 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestBodyStrategy checks whether buffered and streamed request bodies are received as the
+// same payload.
+func TestBodyStrategy(t *testing.T) {
+	received := map[string]map[string]float64{}
+	for _, strategy := range []string{BodyStrategyBuffer, BodyStrategyStream} {
+		var writeRequest prompb.WriteRequest
+		var contentLength int64
+		handler := func(rw http.ResponseWriter, req *http.Request) {
+			contentLength = req.ContentLength
+			body, err := ioutil.ReadAll(req.Body)
+			require.Nil(t, err)
+			decoded, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+			require.Nil(t, err)
+			writeRequest = *decoded
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		exporter := Exporter{
+			config: Config{
+				Endpoint:     server.URL,
+				BodyStrategy: strategy,
+			},
+		}
+		result, err := exporter.ExportWithResult(context.Background(), getHistogramCheckpoint(t))
+		server.Close()
+		require.Nil(t, err)
+		require.Equal(t, 6, result.SeriesSent)
+		require.NotZero(t, result.BytesSent)
+
+		// Streamed bodies are sent with chunked transfer encoding.
+		if strategy == BodyStrategyStream {
+			require.Equal(t, int64(-1), contentLength)
+		} else {
+			require.Equal(t, int64(result.BytesSent), contentLength)
+		}
+		received[strategy] = timeSeriesValues(writeRequest.Timeseries)
+	}
+
+	require.Len(t, received[BodyStrategyBuffer], 6)
+	require.Equal(t, received[BodyStrategyBuffer], received[BodyStrategyStream])
+}
+
+// TestEncodeMessage checks whether messages encoded one TimeSeries at a time are the same
+// as the ones buildMessage creates, including messages spanning several Snappy blocks.
+func TestEncodeMessage(t *testing.T) {
+	var timeSeries []*prompb.TimeSeries
+	for i := 0; i < 1000; i++ {
+		timeSeries = append(timeSeries, &prompb.TimeSeries{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "id", Value: fmt.Sprintf("%d-%s", i, strings.Repeat("x", i%200))},
+			},
+			Samples: []prompb.Sample{{Value: float64(i), Timestamp: int64(i)}},
+		})
+	}
+	marshaled, err := proto.Marshal(&prompb.WriteRequest{Timeseries: timeSeries})
+	require.Nil(t, err)
+	require.True(t, len(marshaled) > 2*snappyBlockSize)
+
+	tests := []struct {
+		compression string
+		decompress  func([]byte) ([]byte, error)
+	}{
+		{
+			compression: CompressionSnappy,
+		},
+		{
+			compression: CompressionGzip,
+			decompress: func(message []byte) ([]byte, error) {
+				reader, err := gzip.NewReader(bytes.NewReader(message))
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(reader)
+			},
+		},
+		{
+			compression: CompressionNone,
+			decompress: func(message []byte) ([]byte, error) {
+				return message, nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.compression, func(t *testing.T) {
+			exporter := Exporter{config: Config{Compression: test.compression}}
+			for _, series := range [][]*prompb.TimeSeries{nil, timeSeries[:1], timeSeries} {
+				var streamed bytes.Buffer
+				require.Nil(t, exporter.encodeMessage(&streamed, series))
+
+				// Snappy output is identical, gzip output only after decompressing.
+				if test.decompress == nil {
+					buffered, err := exporter.buildMessage(series)
+					require.Nil(t, err)
+					require.Equal(t, buffered, streamed.Bytes())
+					continue
+				}
+				want, err := proto.Marshal(&prompb.WriteRequest{Timeseries: series})
+				require.Nil(t, err)
+				got, err := test.decompress(streamed.Bytes())
+				require.Nil(t, err)
+				require.True(t, bytes.Equal(want, got))
+			}
+		})
+	}
+}
+
+// closeRecorder is a body that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+// Close records that the body was closed.
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+// TestSendBodyEarlyReturn checks whether the body of a request is only created once the
+// request may be sent, and is closed when the request cannot be built.
+func TestSendBodyEarlyReturn(t *testing.T) {
+	t.Run("In-flight limit reached", func(t *testing.T) {
+		exporter := Exporter{
+			config: Config{
+				Endpoint:            "http://localhost",
+				MaxInFlightRequests: 1,
+				InFlightPolicy:      InFlightPolicySkip,
+			},
+		}
+		release, err := exporter.acquireInFlight(context.Background())
+		require.Nil(t, err)
+		defer release()
+
+		created := false
+		body := func() io.Reader {
+			created = true
+			return &closeRecorder{Reader: &bytes.Buffer{}}
+		}
+		err = exporter.sendBody(context.Background(), body, &ExportResult{})
+		require.Equal(t, ErrTooManyInFlightRequests, err)
+		require.False(t, created)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		exporter := Exporter{
+			config: Config{
+				Endpoint: "http://[::1",
+			},
+		}
+		recorder := &closeRecorder{Reader: &bytes.Buffer{}}
+		err := exporter.sendBody(context.Background(), func() io.Reader { return recorder }, &ExportResult{})
+		require.Error(t, err)
+		require.True(t, recorder.closed)
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// buildInfoName is the name of the series pushed when EmitBuildInfo is set.
+	buildInfoName = "otel_cortex_exporter_build_info"
+
+	// modulePath is the path of the module the Exporter is built from, which is used
+	// to look up its version.
+	modulePath = "go.opentelemetry.io/contrib/exporters/metric/cortex"
+)
+
+// Commit is reported in the commit label of the build-info series. It can be set at
+// build time with -ldflags "-X go.opentelemetry.io/contrib/exporters/metric/cortex.Commit=<commit>".
+var Commit = "unknown"
+
+// moduleVersion returns the version of the Exporter's module as recorded in the
+// binary's build information.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// buildInfoTimeSeries returns a TimeSeries with a constant value of 1 whose labels
+// describe the build of the Exporter.
+func buildInfoTimeSeries(now time.Time) *prompb.TimeSeries {
+	return &prompb.TimeSeries{
+		Labels: []*prompb.Label{
+			{Name: "__name__", Value: buildInfoName},
+			{Name: "commit", Value: Commit},
+			{Name: "goversion", Value: runtime.Version()},
+			{Name: "version", Value: moduleVersion()},
+		},
+		Samples: []prompb.Sample{{
+			Value:     1,
+			Timestamp: now.UnixNano() / int64(time.Millisecond),
+		}},
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestEmitBuildInfo checks whether the build-info series is pushed alongside the
+// converted records only when EmitBuildInfo is set.
+func TestEmitBuildInfo(t *testing.T) {
+	tests := []struct {
+		testName      string
+		emitBuildInfo bool
+		wantSeries    int
+	}{
+		{
+			testName:      "EmitBuildInfo enabled",
+			emitBuildInfo: true,
+			wantSeries:    2,
+		},
+		{
+			testName:      "EmitBuildInfo disabled",
+			emitBuildInfo: false,
+			wantSeries:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var received prompb.WriteRequest
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				require.Nil(t, err)
+				decoded, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+				require.Nil(t, err)
+				received = *decoded
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:      server.URL,
+					EmitBuildInfo: test.emitBuildInfo,
+				},
+			}
+			require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+			require.Len(t, received.Timeseries, test.wantSeries)
+
+			if !test.emitBuildInfo {
+				return
+			}
+			buildInfo := received.Timeseries[len(received.Timeseries)-1]
+			require.Equal(t, []*prompb.Label{
+				{Name: "__name__", Value: "otel_cortex_exporter_build_info"},
+				{Name: "commit", Value: "unknown"},
+				{Name: "goversion", Value: runtime.Version()},
+				{Name: "version", Value: moduleVersion()},
+			}, buildInfo.Labels)
+			require.Len(t, buildInfo.Samples, 1)
+			require.Equal(t, 1.0, buildInfo.Samples[0].Value)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// certExpiryCheckInterval is how often the client certificate is checked for expiry
+// during pushes after the check when the Exporter is created.
+const certExpiryCheckInterval = time.Hour
+
+var (
+	// ErrInvalidClientCertificate occurs when the cert_file in the TLSConfig does not hold
+	// a PEM-encoded certificate.
+	ErrInvalidClientCertificate = fmt.Errorf("Client certificate file does not contain a certificate")
+)
+
+// checkCertExpiry warns when the client certificate in the TLSConfig expires within
+// CertExpiryWarnWindow, since pushes fail once it expired. The certificate is checked when
+// the Exporter is created and then at most once every certExpiryCheckInterval.
+func (e *Exporter) checkCertExpiry(now time.Time) {
+	certFile := e.config.TLSConfig["cert_file"]
+	if certFile == "" {
+		return
+	}
+
+	e.lock.Lock()
+	if !e.certExpiryChecked.IsZero() && now.Sub(e.certExpiryChecked) < certExpiryCheckInterval {
+		e.lock.Unlock()
+		return
+	}
+	e.certExpiryChecked = now
+	e.lock.Unlock()
+
+	notAfter, err := certNotAfter(certFile)
+	if err != nil {
+		e.logf("Could not check the expiry of the client certificate %s: %v", certFile, err)
+		return
+	}
+	if remaining := notAfter.Sub(now); remaining < e.config.CertExpiryWarnWindow {
+		e.logf("Client certificate %s expires at %s, in %s", certFile, notAfter.UTC().Format(time.RFC3339), remaining.Round(time.Second))
+		e.addCertExpiryWarning()
+	}
+}
+
+// certNotAfter returns the time after which the first certificate in a PEM file is no
+// longer valid.
+func certNotAfter(certFile string) (time.Time, error) {
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, ErrInvalidClientCertificate
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckCertExpiry checks whether a warning is logged and counted only when the client
+// certificate expires within CertExpiryWarnWindow.
+func TestCheckCertExpiry(t *testing.T) {
+	// The generated certificate expires in 5 minutes.
+	_, _, err := generateCACertFiles("./expiring_cert.pem", "./expiring_key.pem")
+	require.Nil(t, err)
+	defer os.Remove("./expiring_cert.pem")
+	defer os.Remove("./expiring_key.pem")
+
+	tests := []struct {
+		testName    string
+		window      time.Duration
+		wantWarning bool
+	}{
+		{
+			testName:    "Expiry within the window",
+			window:      time.Hour,
+			wantWarning: true,
+		},
+		{
+			testName:    "Expiry after the window",
+			window:      time.Minute,
+			wantWarning: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var logs bytes.Buffer
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					TLSConfig:            map[string]string{"cert_file": "./expiring_cert.pem"},
+					CertExpiryWarnWindow: test.window,
+					Logger:               log.New(&logs, "", 0),
+					MeterProvider:        controller.Provider(),
+				},
+			}
+
+			exporter.checkCertExpiry(time.Now())
+			if !test.wantWarning {
+				require.Empty(t, logs.String())
+				require.Empty(t, selfMetricValues(t, controller))
+				return
+			}
+			require.True(t, strings.HasPrefix(logs.String(), "Client certificate ./expiring_cert.pem expires at "), logs.String())
+			require.Equal(t, map[string]float64{
+				"cortex_exporter_cert_expiry_warnings_total{}": 1,
+			}, selfMetricValues(t, controller))
+
+			// The certificate is not checked again until the check interval passed.
+			logs.Reset()
+			exporter.checkCertExpiry(time.Now().Add(time.Minute))
+			require.Empty(t, logs.String())
+			exporter.checkCertExpiry(time.Now().Add(certExpiryCheckInterval))
+			require.NotEmpty(t, logs.String())
+		})
+	}
+}
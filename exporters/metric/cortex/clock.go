@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"time"
+
+	controllerTime "go.opentelemetry.io/otel/sdk/metric/controller/time"
+)
+
+// immediateClock is a Clock for the push Controller whose Tickers tick once right away
+// and then every period, so that the first push happens when the Controller starts
+// instead of after a full PushInterval.
+type immediateClock struct {
+	controllerTime.Clock
+}
+
+// immediateTicker forwards the ticks of a Ticker after an initial tick.
+type immediateTicker struct {
+	ticker controllerTime.Ticker
+	ch     chan time.Time
+	stop   chan struct{}
+}
+
+var _ controllerTime.Clock = immediateClock{}
+var _ controllerTime.Ticker = (*immediateTicker)(nil)
+
+// Ticker returns a Ticker that ticks right away and then every period.
+func (c immediateClock) Ticker(period time.Duration) controllerTime.Ticker {
+	t := &immediateTicker{
+		ticker: c.Clock.Ticker(period),
+		ch:     make(chan time.Time, 1),
+		stop:   make(chan struct{}),
+	}
+	t.ch <- c.Now()
+
+	go func() {
+		for {
+			select {
+			case <-t.stop:
+				return
+			case tick := <-t.ticker.C():
+				select {
+				case t.ch <- tick:
+				case <-t.stop:
+					return
+				}
+			}
+		}
+	}()
+	return t
+}
+
+// Stop stops the Ticker. No more ticks are sent after it returns.
+func (t *immediateTicker) Stop() {
+	close(t.stop)
+	t.ticker.Stop()
+}
+
+// C returns the channel the ticks are sent on.
+func (t *immediateTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+// waitClock tells the time and waits, for the token buckets of PerTenantRateLimits. Tests
+// replace it so that they do not depend on the wall clock.
+type waitClock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realWaitClock is a waitClock that uses the wall clock.
+type realWaitClock struct{}
+
+// Now returns the current time.
+func (realWaitClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep waits for the duration to pass or for the context to be done.
+func (realWaitClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, d)
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/label"
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	// SanitizationCollisionPolicyError fails a push that contains a record with label keys
+	// that are sanitized to the same label name.
+	SanitizationCollisionPolicyError = "error"
+
+	// SanitizationCollisionPolicySuffix keeps the first of the colliding keys and adds a
+	// "_<n>" suffix to the names of the others.
+	SanitizationCollisionPolicySuffix = "suffix"
+
+	// SanitizationCollisionPolicyFirst keeps the first of the colliding keys and drops the
+	// others.
+	SanitizationCollisionPolicyFirst = "first"
+)
+
+var (
+	// ErrLabelCollision occurs when label keys of a record are sanitized to the same label
+	// name and SanitizationCollisionPolicy is "error".
+	ErrLabelCollision = fmt.Errorf("Label keys collide after sanitization")
+)
+
+// resolveLabelCollisions returns a copy of a record whose labels are its sanitized series
+// and resource labels, with keys that are sanitized to the same name, such as "a.b" and
+// "a_b", handled according to SanitizationCollisionPolicy. Keys are ordered by their
+// original name, so the same key is always the first. Records without collisions are
+// returned as they are.
+func (e *Exporter) resolveLabelCollisions(record metric.Record) (metric.Record, error) {
+	var kvs []kv.KeyValue
+	taken := map[string]bool{}
+	collision := false
+	mi := label.NewMergeIterator(record.Labels(), record.Resource().LabelSet())
+	for mi.Next() {
+		l := mi.Label()
+		name := sanitize(string(l.Key))
+		collision = collision || taken[name]
+		taken[name] = true
+		kvs = append(kvs, kv.KeyValue{Key: kv.Key(name), Value: l.Value})
+	}
+	if !collision {
+		return record, nil
+	}
+	if e.config.SanitizationCollisionPolicy == SanitizationCollisionPolicyError {
+		return record, ErrLabelCollision
+	}
+
+	// The merge iterator returns keys in sorted order, so the first key with a name is the
+	// one that is kept.
+	seen := map[kv.Key]bool{}
+	resolved := kvs[:0]
+	for _, l := range kvs {
+		if seen[l.Key] {
+			if e.config.SanitizationCollisionPolicy == SanitizationCollisionPolicyFirst {
+				continue
+			}
+			l.Key = suffixedKey(l.Key, taken)
+		}
+		seen[l.Key] = true
+		resolved = append(resolved, l)
+	}
+
+	labels := label.NewSet(resolved...)
+	return metric.NewRecord(record.Descriptor(), &labels, resource.Empty(), record.Aggregation(), record.StartTime(), record.EndTime()), nil
+}
+
+// suffixedKey returns the key with the lowest "_<n>" suffix that is not taken yet, and
+// marks it as taken.
+func suffixedKey(key kv.Key, taken map[string]bool) kv.Key {
+	for n := 1; ; n++ {
+		name := string(key) + "_" + strconv.Itoa(n)
+		if !taken[name] {
+			taken[name] = true
+			return kv.Key(name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestSanitizationCollisionPolicy checks whether label keys that are sanitized to the
+// same name are handled according to SanitizationCollisionPolicy.
+func TestSanitizationCollisionPolicy(t *testing.T) {
+	tests := []struct {
+		testName   string
+		policy     string
+		wantLabels []*prompb.Label
+		wantErr    error
+	}{
+		{
+			testName: "Error",
+			policy:   SanitizationCollisionPolicyError,
+			wantErr:  ErrLabelCollision,
+		},
+		{
+			testName: "Suffix",
+			policy:   SanitizationCollisionPolicySuffix,
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "a_b", Value: "dot"},
+				{Name: "a_b_1", Value: "underscore"},
+			},
+		},
+		{
+			testName: "First",
+			policy:   SanitizationCollisionPolicyFirst,
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "a_b", Value: "dot"},
+			},
+		},
+	}
+
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("a_b", "underscore"), kv.String("a.b", "dot"))
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{config: Config{SanitizationCollisionPolicy: test.policy}}
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+			if test.wantErr != nil {
+				require.Equal(t, test.wantErr, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 1)
+			require.ElementsMatch(t, test.wantLabels, timeSeries[0].Labels)
+		})
+	}
+}
+
+// TestSuffixedKey checks whether suffixes that are already taken are skipped.
+func TestSuffixedKey(t *testing.T) {
+	taken := map[string]bool{"a_b": true, "a_b_1": true}
+	require.Equal(t, kv.Key("a_b_2"), suffixedKey("a_b", taken))
+	require.Equal(t, kv.Key("a_b_3"), suffixedKey("a_b", taken))
+}
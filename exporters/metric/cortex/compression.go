@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// CompressionSnappy compresses request bodies with Snappy, which is what the remote
+	// write protocol specifies. This is the default.
+	CompressionSnappy = "snappy"
+
+	// CompressionGzip compresses request bodies with gzip, which some ingestion proxies
+	// in front of Cortex prefer.
+	CompressionGzip = "gzip"
+
+	// CompressionNone sends request bodies uncompressed, which is useful for debugging.
+	CompressionNone = "none"
+)
+
+// compress compresses a marshaled WriteRequest according to Compression.
+func (e *Exporter) compress(message []byte) ([]byte, error) {
+	switch e.config.Compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(message); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionNone:
+		return message, nil
+	}
+	return snappy.Encode(nil, message), nil
+}
+
+// contentEncoding returns the Content-Encoding header of requests compressed according to
+// Compression, or an empty string if they are not compressed.
+func (e *Exporter) contentEncoding() string {
+	switch e.config.Compression {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionNone:
+		return ""
+	}
+	return "snappy"
+}
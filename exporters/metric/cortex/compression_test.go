@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestCompression checks whether request bodies are compressed and labeled with a
+// Content-Encoding header according to Compression.
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		testName     string
+		compression  string
+		wantEncoding string
+	}{
+		{"Default", "", "snappy"},
+		{"Snappy", CompressionSnappy, "snappy"},
+		{"Gzip", CompressionGzip, "gzip"},
+		{"None", CompressionNone, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var encoding string
+			var values map[string]float64
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				encoding = req.Header.Get("Content-Encoding")
+				body, err := ioutil.ReadAll(req.Body)
+				require.Nil(t, err)
+				writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+				require.Nil(t, err)
+				values = timeSeriesValues(writeRequest.Timeseries)
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:    server.URL,
+					Compression: test.compression,
+				},
+			}
+			require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 321)))
+			require.Equal(t, test.wantEncoding, encoding)
+			require.Equal(t, map[string]float64{"metric_name": 321}, values)
+		})
+	}
+}
@@ -15,9 +15,19 @@
 package cortex
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/api/metric"
 )
 
 var (
@@ -29,26 +39,232 @@ var (
 	// `bearer_token_file`.
 	ErrTwoBearerTokens = fmt.Errorf("Cannot have two bearer tokens in the YAML file")
 
-	// ErrConflictingAuthorization occurs when the YAML file contains both BasicAuth and
-	// bearer token authorization
-	ErrConflictingAuthorization = fmt.Errorf("Cannot have both basic auth and bearer token authorization")
+	// ErrConflictingAuthorization occurs when the YAML file contains more than one of
+	// BasicAuth, bearer token, and OAuth2 authorization.
+	ErrConflictingAuthorization = fmt.Errorf("Cannot have more than one of basic auth, bearer token, and OAuth2 authorization")
+
+	// ErrConflictingContentEncoding occurs when the headers contain a Content-Encoding
+	// header, which conflicts with the compression applied by the Exporter.
+	ErrConflictingContentEncoding = fmt.Errorf("Cannot set a Content-Encoding header since requests are compressed by the Exporter")
+
+	// ErrInvalidInFlightPolicy occurs when the YAML file contains an in_flight_policy
+	// other than "wait" or "skip".
+	ErrInvalidInFlightPolicy = fmt.Errorf("In-flight policy must be either wait or skip")
+
+	// ErrInvalidShardLabel occurs when the YAML file contains a shard_label without a
+	// name or a value.
+	ErrInvalidShardLabel = fmt.Errorf("Shard label must have both a name and a value")
+
+	// ErrInvalidLabelPrecedence occurs when the YAML file contains a label_precedence
+	// that does not list each of "series", "resource", and "external" exactly once.
+	ErrInvalidLabelPrecedence = fmt.Errorf("Label precedence must list series, resource, and external exactly once")
+
+	// ErrInvalidLabelNameLengthPolicy occurs when the YAML file contains a
+	// label_name_length_policy other than "truncate" or "drop".
+	ErrInvalidLabelNameLengthPolicy = fmt.Errorf("Label name length policy must be either truncate or drop")
+
+	// ErrConflictingDedup occurs when the YAML file contains both
+	// `dedup_unchanged_interval` and `only_send_updated`.
+	ErrConflictingDedup = fmt.Errorf("Cannot have both dedup_unchanged_interval and only_send_updated")
+
+	// ErrInvalidTimestampMonotonicPolicy occurs when the YAML file contains a
+	// timestamp_monotonic_policy other than "error" or "drop".
+	ErrInvalidTimestampMonotonicPolicy = fmt.Errorf("Timestamp monotonic policy must be either error or drop")
+
+	// ErrInsecureCredentials occurs when the YAML file contains `basic_auth` or a bearer
+	// token for an http:// endpoint without `allow_insecure`.
+	ErrInsecureCredentials = fmt.Errorf("Cannot send credentials over plaintext http without allow_insecure")
+
+	// ErrInvalidRelabelConfig occurs when the YAML file contains a relabel config with an
+	// unsupported action, an invalid regex, or a replace action without `target_label`.
+	ErrInvalidRelabelConfig = fmt.Errorf("Relabel config must have a valid action and regex")
+
+	// ErrInvalidDuplicateScopePolicy occurs when the YAML file contains a
+	// duplicate_scope_policy other than "merge" or "error".
+	ErrInvalidDuplicateScopePolicy = fmt.Errorf("Duplicate scope policy must be either merge or error")
+
+	// ErrInvalidTemporalityMismatchPolicy occurs when the YAML file contains a
+	// temporality_mismatch_policy other than "error", "convert", or "passthrough".
+	ErrInvalidTemporalityMismatchPolicy = fmt.Errorf("Temporality mismatch policy must be either error, convert, or passthrough")
+
+	// ErrInvalidPartialCollectionPolicy occurs when the YAML file contains a
+	// partial_collection_policy other than "abort" or "send".
+	ErrInvalidPartialCollectionPolicy = fmt.Errorf("Partial collection policy must be either abort or send")
+
+	// ErrInvalidMetricNameSchema occurs when the YAML file contains a metric_name_schema
+	// that is not a valid regular expression.
+	ErrInvalidMetricNameSchema = fmt.Errorf("Metric name schema must be a valid regular expression")
+
+	// ErrInvalidMetricNameFilter occurs when the YAML file contains a pattern in
+	// `metric_name_allowlist` or `metric_name_denylist` that is not a valid regular
+	// expression.
+	ErrInvalidMetricNameFilter = fmt.Errorf("Metric name allowlist and denylist must contain valid regular expressions")
+
+	// ErrInvalidMetricNameSchemaPolicy occurs when the YAML file contains a
+	// metric_name_schema_policy other than "drop" or "error".
+	ErrInvalidMetricNameSchemaPolicy = fmt.Errorf("Metric name schema policy must be either drop or error")
+
+	// ErrInvalidTenantRateLimit occurs when the YAML file contains a rate limit in
+	// `per_tenant_rate_limits` that is not positive.
+	ErrInvalidTenantRateLimit = fmt.Errorf("Tenant rate limits must be positive")
+
+	// ErrInvalidSanitizationCollisionPolicy occurs when the YAML file contains a
+	// sanitization_collision_policy other than "error", "suffix", or "first".
+	ErrInvalidSanitizationCollisionPolicy = fmt.Errorf("Sanitization collision policy must be error, suffix, or first")
+
+	// ErrInvalidValidationFailureMode occurs when the YAML file contains a validation
+	// failure mode other than open or closed.
+	ErrInvalidValidationFailureMode = fmt.Errorf("Validation failure mode must be either open or closed")
+
+	// ErrInvalidCompression occurs when the YAML file contains a compression other than
+	// snappy, gzip, or none.
+	ErrInvalidCompression = fmt.Errorf("Compression must be snappy, gzip, or none")
+
+	// ErrInvalidTenantSharding occurs when the YAML file contains a `tenant_sharding`
+	// without a prefix or with a number of shards that is not positive.
+	ErrInvalidTenantSharding = fmt.Errorf("Tenant sharding requires a prefix and a positive number of shards")
+
+	// ErrInvalidEndpoint occurs when the YAML file contains a `url` that is neither an
+	// absolute URL with a scheme and a host nor a path starting with a slash.
+	ErrInvalidEndpoint = fmt.Errorf("Endpoint must be an absolute URL with a scheme and a host")
+
+	// ErrInvalidCrossScopeNameConflictPolicy occurs when the YAML file contains a
+	// cross_scope_name_conflict_policy other than "error", "scope_prefix", or "first".
+	ErrInvalidCrossScopeNameConflictPolicy = fmt.Errorf("Cross-scope name conflict policy must be error, scope_prefix, or first")
+
+	// ErrInvalidHistogramBucketPolicy occurs when the YAML file contains a
+	// histogram_bucket_policy other than "drop" or "repair".
+	ErrInvalidHistogramBucketPolicy = fmt.Errorf("Histogram bucket policy must be either drop or repair")
+
+	// ErrInvalidAuthCacheSkew occurs when the YAML file contains a negative
+	// `auth_cache_skew`.
+	ErrInvalidAuthCacheSkew = fmt.Errorf("Auth cache skew cannot be negative")
+
+	// ErrInvalidLatencyWindow occurs when the YAML file contains a negative
+	// `latency_window`.
+	ErrInvalidLatencyWindow = fmt.Errorf("Latency window cannot be negative")
+
+	// ErrInvalidConnectionTimeout occurs when the YAML file contains a negative
+	// `dial_timeout` or `tls_handshake_timeout`.
+	ErrInvalidConnectionTimeout = fmt.Errorf("Dial and TLS handshake timeouts cannot be negative")
+
+	// ErrInvalidDrainTimeout occurs when the YAML file contains a negative
+	// `drain_timeout`.
+	ErrInvalidDrainTimeout = fmt.Errorf("Drain timeout cannot be negative")
+
+	// ErrNoWALDirectory occurs when the YAML file enables the WAL in `wal_config` without
+	// a directory.
+	ErrNoWALDirectory = fmt.Errorf("WAL requires a directory")
+
+	// ErrInvalidWALMaxSegments occurs when the YAML file contains a negative
+	// `max_segments` in `wal_config`.
+	ErrInvalidWALMaxSegments = fmt.Errorf("WAL max segments cannot be negative")
+
+	// ErrInvalidDownsample occurs when the YAML file contains a rate in `downsample` that is
+	// not positive.
+	ErrInvalidDownsample = fmt.Errorf("Downsample rates must be positive")
+
+	// ErrInvalidBodyStrategy occurs when the YAML file contains a body_strategy other than
+	// "buffer" or "stream".
+	ErrInvalidBodyStrategy = fmt.Errorf("Body strategy must be either buffer or stream")
 )
 
 // Config contains properties the Exporter uses to export metrics data to Cortex.
 type Config struct {
-	Endpoint            string            `mapstructure:"url"`
-	RemoteTimeout       time.Duration     `mapstructure:"remote_timeout"`
-	Name                string            `mapstructure:"name"`
-	BasicAuth           map[string]string `mapstructure:"basic_auth"`
-	BearerToken         string            `mapstructure:"bearer_token"`
-	BearerTokenFile     string            `mapstructure:"bearer_token_file"`
-	TLSConfig           map[string]string `mapstructure:"tls_config"`
-	ProxyURL            string            `mapstructure:"proxy_url"`
-	PushInterval        time.Duration     `mapstructure:"push_interval"`
-	Quantiles           []float64         `mapstructure:"quantiles"`
-	HistogramBoundaries []float64         `mapstructure:"histogram_boundaries"`
-	Headers             map[string]string `mapstructure:"headers"`
-	Client              *http.Client
+	Endpoint                     string             `mapstructure:"url"`
+	RemoteTimeout                time.Duration      `mapstructure:"remote_timeout"`
+	Name                         string             `mapstructure:"name"`
+	BasicAuth                    map[string]string  `mapstructure:"basic_auth"`
+	BearerToken                  string             `mapstructure:"bearer_token"`
+	BearerTokenFile              string             `mapstructure:"bearer_token_file"`
+	TLSConfig                    map[string]string  `mapstructure:"tls_config"`
+	ProxyURL                     string             `mapstructure:"proxy_url"`
+	PushInterval                 time.Duration      `mapstructure:"push_interval"`
+	Quantiles                    []float64          `mapstructure:"quantiles"`
+	HistogramBoundaries          []float64          `mapstructure:"histogram_boundaries"`
+	Headers                      map[string]string  `mapstructure:"headers"`
+	DedupUnchangedInterval       time.Duration      `mapstructure:"dedup_unchanged_interval"`
+	EmitCreatedSeries            bool               `mapstructure:"emit_created_series"`
+	ConvertConcurrency           int                `mapstructure:"convert_concurrency"`
+	RetryOnDialError             *RetryConfig       `mapstructure:"retry_on_dial_error"`
+	EmitBuildInfo                bool               `mapstructure:"emit_build_info"`
+	MaxInFlightRequests          int                `mapstructure:"max_in_flight_requests"`
+	InFlightPolicy               string             `mapstructure:"in_flight_policy"`
+	ShardLabel                   map[string]string  `mapstructure:"shard_label"`
+	FollowRedirects              bool               `mapstructure:"follow_redirects"`
+	ResponseHeaderTimeout        time.Duration      `mapstructure:"response_header_timeout"`
+	ExternalLabels               map[string]string  `mapstructure:"external_labels"`
+	LabelPrecedence              []string           `mapstructure:"label_precedence"`
+	MaxSamplesPerSeries          int                `mapstructure:"max_samples_per_series"`
+	MaxLabelNameLength           int                `mapstructure:"max_label_name_length"`
+	LabelNameLengthPolicy        string             `mapstructure:"label_name_length_policy"`
+	OnlySendUpdated              bool               `mapstructure:"only_send_updated"`
+	KeepaliveInterval            time.Duration      `mapstructure:"keepalive_interval"`
+	SanitizeLabelValues          bool               `mapstructure:"sanitize_label_values"`
+	MaxSeriesPerRequest          int                `mapstructure:"max_series_per_request"`
+	InterRequestDelay            time.Duration      `mapstructure:"inter_request_delay"`
+	Use100Continue               bool               `mapstructure:"use_100_continue"`
+	TimestampMonotonicPolicy     string             `mapstructure:"timestamp_monotonic_policy"`
+	MaxPushDuration              time.Duration      `mapstructure:"max_push_duration"`
+	CollapseAttributesToJSON     bool               `mapstructure:"collapse_attributes_to_json"`
+	AllowInsecure                bool               `mapstructure:"allow_insecure"`
+	RelabelConfigs               []RelabelConfig    `mapstructure:"relabel_configs"`
+	DuplicateScopePolicy         string             `mapstructure:"duplicate_scope_policy"`
+	ReuseConnections             *bool              `mapstructure:"reuse_connections"`
+	DropEmptyLabels              *bool              `mapstructure:"drop_empty_labels"`
+	MaxTotalSeries               int                `mapstructure:"max_total_series"`
+	TemporalityMismatchPolicy    string             `mapstructure:"temporality_mismatch_policy"`
+	ReportTimestampSkew          bool               `mapstructure:"report_timestamp_skew"`
+	AutoSplitOnFailure           bool               `mapstructure:"auto_split_on_failure"`
+	ContentType                  string             `mapstructure:"content_type"`
+	PartialCollectionPolicy      string             `mapstructure:"partial_collection_policy"`
+	WarmUpConnection             bool               `mapstructure:"warm_up_connection"`
+	MaxSeriesBytes               int                `mapstructure:"max_series_bytes"`
+	PushOnStart                  *bool              `mapstructure:"push_on_start"`
+	MetricNameSchema             string             `mapstructure:"metric_name_schema"`
+	MetricNameSchemaPolicy       string             `mapstructure:"metric_name_schema_policy"`
+	PerTenantRateLimits          map[string]float64 `mapstructure:"per_tenant_rate_limits"`
+	RequiredLabelDefaults        map[string]string  `mapstructure:"required_label_defaults"`
+	DropRuntimeMetrics           bool               `mapstructure:"drop_runtime_metrics"`
+	SanitizationCollisionPolicy  string             `mapstructure:"sanitization_collision_policy"`
+	EmitGapMarkers               bool               `mapstructure:"emit_gap_markers"`
+	UseSRVDiscovery              bool               `mapstructure:"use_srv_discovery"`
+	SRVRefreshInterval           time.Duration      `mapstructure:"srv_refresh_interval"`
+	SeriesWarnThreshold          int                `mapstructure:"series_warn_threshold"`
+	MergeDuplicateHistograms     bool               `mapstructure:"merge_duplicate_histograms"`
+	Downsample                   map[string]int     `mapstructure:"downsample"`
+	EmitPushSequence             bool               `mapstructure:"emit_push_sequence"`
+	CertExpiryWarnWindow         time.Duration      `mapstructure:"cert_expiry_warn_window"`
+	BodyStrategy                 string             `mapstructure:"body_strategy"`
+	DuplicateHistogramCount      bool               `mapstructure:"duplicate_histogram_count"`
+	DetectResourceChanges        bool               `mapstructure:"detect_resource_changes"`
+	MarkStaleOnResourceChange    bool               `mapstructure:"mark_stale_on_resource_change"`
+	MaxSamplesPerRequest         int                `mapstructure:"max_samples_per_request"`
+	OAuth2                       map[string]string  `mapstructure:"oauth2"`
+	AlignTimestamps              bool               `mapstructure:"align_timestamps"`
+	RetryConfig                  *RetryConfig       `mapstructure:"retry_config"`
+	ValidationFailureMode        string             `mapstructure:"validation_failure_mode"`
+	Compression                  string             `mapstructure:"compression"`
+	TenantSharding               *ShardingConfig    `mapstructure:"tenant_sharding"`
+	WALConfig                    *WALConfig         `mapstructure:"wal_config"`
+	AuthCacheSkew                time.Duration      `mapstructure:"auth_cache_skew"`
+	CrossScopeNameConflictPolicy string             `mapstructure:"cross_scope_name_conflict_policy"`
+	LatencyWindow                int                `mapstructure:"latency_window"`
+	DialTimeout                  time.Duration      `mapstructure:"dial_timeout"`
+	TLSHandshakeTimeout          time.Duration      `mapstructure:"tls_handshake_timeout"`
+	DrainTimeout                 time.Duration      `mapstructure:"drain_timeout"`
+	HistogramBucketPolicy        string             `mapstructure:"histogram_bucket_policy"`
+	DryRun                       bool               `mapstructure:"dry_run"`
+	MetricNameAllowlist          []string           `mapstructure:"metric_name_allowlist"`
+	MetricNameDenylist           []string           `mapstructure:"metric_name_denylist"`
+	Client                       *http.Client
+	MeterProvider                metric.Provider
+	EventChan                    chan<- PushEvent
+	Logger                       *log.Logger
+	LabelTransform               func([]*prompb.Label) []*prompb.Label
+	SeriesPriority               func(*prompb.TimeSeries) int
+	ValueTransform               func(metricName string, value float64) float64
+	DialContext                  func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // Validate checks a Config struct for missing required properties and property conflicts.
@@ -66,6 +282,138 @@ func (c *Config) Validate() error {
 	if c.BearerToken != "" && c.BearerTokenFile != "" {
 		return ErrTwoBearerTokens
 	}
+	if c.Endpoint != "" && !validEndpoint(c.Endpoint) {
+		return ErrInvalidEndpoint
+	}
+	if c.OAuth2 != nil {
+		if c.BasicAuth != nil || c.BearerToken != "" || c.BearerTokenFile != "" {
+			return ErrConflictingAuthorization
+		}
+		if c.OAuth2["client_id"] == "" || c.OAuth2["client_secret"] == "" || c.OAuth2["token_url"] == "" {
+			return ErrInvalidOAuth2Config
+		}
+	}
+	if err := validateTLSConfig(c.TLSConfig); err != nil {
+		return err
+	}
+	// Credentials sent over plaintext can be read by anyone on the network path.
+	hasCredentials := c.BasicAuth != nil || c.BearerToken != "" || c.BearerTokenFile != "" || c.OAuth2 != nil
+	if hasCredentials && !c.AllowInsecure && strings.HasPrefix(strings.ToLower(c.Endpoint), "http://") {
+		return ErrInsecureCredentials
+	}
+	// The Exporter sets the Content-Encoding header itself. A second value would make
+	// Cortex either reject the request or decode the body twice.
+	for name := range c.Headers {
+		if http.CanonicalHeaderKey(name) == "Content-Encoding" {
+			return ErrConflictingContentEncoding
+		}
+	}
+	if c.InFlightPolicy != "" && c.InFlightPolicy != InFlightPolicyWait && c.InFlightPolicy != InFlightPolicySkip {
+		return ErrInvalidInFlightPolicy
+	}
+	if c.ShardLabel != nil && (c.ShardLabel["name"] == "" || c.ShardLabel["value"] == "") {
+		return ErrInvalidShardLabel
+	}
+	if c.LabelPrecedence != nil && !validLabelPrecedence(c.LabelPrecedence) {
+		return ErrInvalidLabelPrecedence
+	}
+	if c.LabelNameLengthPolicy != "" && c.LabelNameLengthPolicy != LabelNameLengthPolicyTruncate && c.LabelNameLengthPolicy != LabelNameLengthPolicyDrop {
+		return ErrInvalidLabelNameLengthPolicy
+	}
+	if c.DuplicateScopePolicy != "" && c.DuplicateScopePolicy != DuplicateScopePolicyMerge && c.DuplicateScopePolicy != DuplicateScopePolicyError {
+		return ErrInvalidDuplicateScopePolicy
+	}
+	switch c.TemporalityMismatchPolicy {
+	case "", TemporalityMismatchPolicyError, TemporalityMismatchPolicyConvert, TemporalityMismatchPolicyPassthrough:
+	default:
+		return ErrInvalidTemporalityMismatchPolicy
+	}
+	if c.PartialCollectionPolicy != "" && c.PartialCollectionPolicy != PartialCollectionPolicyAbort && c.PartialCollectionPolicy != PartialCollectionPolicySend {
+		return ErrInvalidPartialCollectionPolicy
+	}
+	if c.MetricNameSchema != "" {
+		if _, err := regexp.Compile(anchorMetricNameSchema(c.MetricNameSchema)); err != nil {
+			return ErrInvalidMetricNameSchema
+		}
+	}
+	if _, err := compileMetricNameFilter(c.MetricNameAllowlist); err != nil {
+		return ErrInvalidMetricNameFilter
+	}
+	if _, err := compileMetricNameFilter(c.MetricNameDenylist); err != nil {
+		return ErrInvalidMetricNameFilter
+	}
+	if c.MetricNameSchemaPolicy != "" && c.MetricNameSchemaPolicy != MetricNameSchemaPolicyDrop && c.MetricNameSchemaPolicy != MetricNameSchemaPolicyError {
+		return ErrInvalidMetricNameSchemaPolicy
+	}
+	switch c.CrossScopeNameConflictPolicy {
+	case "", CrossScopeNameConflictPolicyError, CrossScopeNameConflictPolicyScopePrefix, CrossScopeNameConflictPolicyFirst:
+	default:
+		return ErrInvalidCrossScopeNameConflictPolicy
+	}
+	switch c.HistogramBucketPolicy {
+	case "", HistogramBucketPolicyDrop, HistogramBucketPolicyRepair:
+	default:
+		return ErrInvalidHistogramBucketPolicy
+	}
+	if c.AuthCacheSkew < 0 {
+		return ErrInvalidAuthCacheSkew
+	}
+	if c.LatencyWindow < 0 {
+		return ErrInvalidLatencyWindow
+	}
+	if c.DialTimeout < 0 || c.TLSHandshakeTimeout < 0 {
+		return ErrInvalidConnectionTimeout
+	}
+	if c.DrainTimeout < 0 {
+		return ErrInvalidDrainTimeout
+	}
+	if c.WALConfig != nil && c.WALConfig.Enabled && c.WALConfig.Directory == "" {
+		return ErrNoWALDirectory
+	}
+	if c.WALConfig != nil && c.WALConfig.MaxSegments < 0 {
+		return ErrInvalidWALMaxSegments
+	}
+	if c.TenantSharding != nil && (c.TenantSharding.Prefix == "" || c.TenantSharding.Shards <= 0) {
+		return ErrInvalidTenantSharding
+	}
+	switch c.Compression {
+	case "", CompressionSnappy, CompressionGzip, CompressionNone:
+	default:
+		return ErrInvalidCompression
+	}
+	if c.ValidationFailureMode != "" && c.ValidationFailureMode != ValidationFailureModeOpen && c.ValidationFailureMode != ValidationFailureModeClosed {
+		return ErrInvalidValidationFailureMode
+	}
+	switch c.SanitizationCollisionPolicy {
+	case "", SanitizationCollisionPolicyError, SanitizationCollisionPolicySuffix, SanitizationCollisionPolicyFirst:
+	default:
+		return ErrInvalidSanitizationCollisionPolicy
+	}
+	if c.BodyStrategy != "" && c.BodyStrategy != BodyStrategyBuffer && c.BodyStrategy != BodyStrategyStream {
+		return ErrInvalidBodyStrategy
+	}
+	for _, rate := range c.PerTenantRateLimits {
+		if rate <= 0 {
+			return ErrInvalidTenantRateLimit
+		}
+	}
+	for _, n := range c.Downsample {
+		if n <= 0 {
+			return ErrInvalidDownsample
+		}
+	}
+	for i := range c.RelabelConfigs {
+		c.RelabelConfigs[i].setDefaults()
+		if !c.RelabelConfigs[i].valid() {
+			return ErrInvalidRelabelConfig
+		}
+	}
+	if c.DedupUnchangedInterval > 0 && c.OnlySendUpdated {
+		return ErrConflictingDedup
+	}
+	if c.TimestampMonotonicPolicy != "" && c.TimestampMonotonicPolicy != TimestampMonotonicPolicyError && c.TimestampMonotonicPolicy != TimestampMonotonicPolicyDrop {
+		return ErrInvalidTimestampMonotonicPolicy
+	}
 
 	// Add default values for missing properties.
 	if c.Endpoint == "" {
@@ -78,6 +426,115 @@ func (c *Config) Validate() error {
 	if c.PushInterval == 0 {
 		c.PushInterval = 10 * time.Second
 	}
+	if c.RetryOnDialError != nil {
+		c.RetryOnDialError.setDefaults()
+	}
+	if c.RetryConfig != nil {
+		c.RetryConfig.setDefaults()
+	}
+	if c.WALConfig != nil && c.WALConfig.Enabled && c.WALConfig.TruncateFrequency == 0 {
+		c.WALConfig.TruncateFrequency = time.Minute
+	}
+	if c.MaxInFlightRequests > 0 && c.InFlightPolicy == "" {
+		c.InFlightPolicy = InFlightPolicyWait
+	}
+	if c.MaxLabelNameLength > 0 && c.LabelNameLengthPolicy == "" {
+		c.LabelNameLengthPolicy = LabelNameLengthPolicyTruncate
+	}
+	// In the closed validation failure mode, a series whose metric name does not match
+	// the schema fails the push.
+	if c.MetricNameSchema != "" && c.MetricNameSchemaPolicy == "" {
+		c.MetricNameSchemaPolicy = MetricNameSchemaPolicyDrop
+		if c.ValidationFailureMode == ValidationFailureModeClosed {
+			c.MetricNameSchemaPolicy = MetricNameSchemaPolicyError
+		}
+	}
+	if c.UseSRVDiscovery && c.SRVRefreshInterval == 0 {
+		c.SRVRefreshInterval = 30 * time.Second
+	}
+	// Unchanged series are sent again before Prometheus-based backends consider them
+	// stale, which happens after 5 minutes.
+	if c.OnlySendUpdated && c.KeepaliveInterval == 0 {
+		c.KeepaliveInterval = 4 * time.Minute
+	}
 
 	return nil
 }
+
+// validEndpoint reports whether an endpoint is an absolute URL with a scheme and a host,
+// a Unix domain socket endpoint, or a path starting with a slash like the default
+// endpoint. Endpoints like "cortex.example.com/push" are parsed as relative paths and
+// would only fail when the first request is sent.
+func validEndpoint(endpoint string) bool {
+	if _, _, ok := parseUnixEndpoint(endpoint); ok {
+		return true
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return u.Host == "" && strings.HasPrefix(u.Path, "/")
+	}
+	return u.Host != ""
+}
+
+// redacted replaces the values of secrets in the Config returned by Effective.
+const redacted = "<redacted>"
+
+// Effective returns a copy of the Config with the default values Validate adds, so that
+// the values in use can be logged. Passwords, bearer tokens, OAuth2 client secrets, and
+// Authorization headers are redacted. The Config itself is not changed. Validation errors
+// are ignored, so Validate should be used to check the Config.
+func (c Config) Effective() Config {
+	// Copy the properties Validate changes in place so that the Config is not changed.
+	if c.RetryOnDialError != nil {
+		retry := *c.RetryOnDialError
+		c.RetryOnDialError = &retry
+	}
+	if c.RetryConfig != nil {
+		retry := *c.RetryConfig
+		c.RetryConfig = &retry
+	}
+	if c.WALConfig != nil {
+		wal := *c.WALConfig
+		c.WALConfig = &wal
+	}
+	c.RelabelConfigs = append([]RelabelConfig(nil), c.RelabelConfigs...)
+	_ = c.Validate()
+
+	if c.BasicAuth != nil {
+		basicAuth := make(map[string]string, len(c.BasicAuth))
+		for key, value := range c.BasicAuth {
+			if key == "password" && value != "" {
+				value = redacted
+			}
+			basicAuth[key] = value
+		}
+		c.BasicAuth = basicAuth
+	}
+	if c.BearerToken != "" {
+		c.BearerToken = redacted
+	}
+	if c.OAuth2 != nil {
+		oauth2 := make(map[string]string, len(c.OAuth2))
+		for key, value := range c.OAuth2 {
+			if key == "client_secret" && value != "" {
+				value = redacted
+			}
+			oauth2[key] = value
+		}
+		c.OAuth2 = oauth2
+	}
+	if c.Headers != nil {
+		headers := make(map[string]string, len(c.Headers))
+		for name, value := range c.Headers {
+			if http.CanonicalHeaderKey(name) == "Authorization" {
+				value = redacted
+			}
+			headers[name] = value
+		}
+		c.Headers = headers
+	}
+	return c
+}
@@ -110,3 +110,378 @@ var exampleTwoAuthConfig = cortex.Config{
 	},
 	BearerToken: "bearer_token",
 }
+
+// Example Config struct with a Content-Encoding header that conflicts with the Snappy
+// compression applied by the Exporter.
+var exampleContentEncodingConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	Headers: map[string]string{
+		"content-encoding": "gzip",
+	},
+}
+
+// Example Config struct with an in_flight_policy that is neither "wait" nor "skip".
+var exampleInvalidInFlightPolicyConfig = cortex.Config{
+	Endpoint:            "/api/prom/push",
+	Name:                "Config",
+	RemoteTimeout:       30 * time.Second,
+	PushInterval:        10 * time.Second,
+	MaxInFlightRequests: 2,
+	InFlightPolicy:      "drop",
+}
+
+// Example Config struct with MaxInFlightRequests but no in_flight_policy.
+var exampleNoInFlightPolicyConfig = cortex.Config{
+	Endpoint:            "/api/prom/push",
+	Name:                "Config",
+	RemoteTimeout:       30 * time.Second,
+	PushInterval:        10 * time.Second,
+	MaxInFlightRequests: 2,
+}
+
+// Example Config struct with MaxInFlightRequests and the default in_flight_policy.
+var validatedInFlightPolicyConfig = cortex.Config{
+	Endpoint:            "/api/prom/push",
+	Name:                "Config",
+	RemoteTimeout:       30 * time.Second,
+	PushInterval:        10 * time.Second,
+	MaxInFlightRequests: 2,
+	InFlightPolicy:      cortex.InFlightPolicyWait,
+}
+
+// Example Config struct with a shard_label that has no value.
+var exampleInvalidShardLabelConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	ShardLabel: map[string]string{
+		"name": "shard",
+	},
+}
+
+// Example Config struct with a label_precedence that lists a source twice.
+var exampleInvalidLabelPrecedenceConfig = cortex.Config{
+	Endpoint:        "/api/prom/push",
+	Name:            "Config",
+	RemoteTimeout:   30 * time.Second,
+	PushInterval:    10 * time.Second,
+	LabelPrecedence: []string{"series", "series", "external"},
+}
+
+// Example Config struct with a label_name_length_policy other than "truncate" or "drop".
+var exampleInvalidLabelNameLengthPolicyConfig = cortex.Config{
+	Endpoint:              "/api/prom/push",
+	Name:                  "Config",
+	RemoteTimeout:         30 * time.Second,
+	PushInterval:          10 * time.Second,
+	MaxLabelNameLength:    64,
+	LabelNameLengthPolicy: "reject",
+}
+
+// Example Config struct with both dedup_unchanged_interval and only_send_updated.
+var exampleConflictingDedupConfig = cortex.Config{
+	Endpoint:               "/api/prom/push",
+	Name:                   "Config",
+	RemoteTimeout:          30 * time.Second,
+	PushInterval:           10 * time.Second,
+	DedupUnchangedInterval: time.Minute,
+	OnlySendUpdated:        true,
+}
+
+// Example Config struct with a timestamp_monotonic_policy other than "error" or "drop".
+var exampleInvalidTimestampMonotonicPolicyConfig = cortex.Config{
+	Endpoint:                 "/api/prom/push",
+	Name:                     "Config",
+	RemoteTimeout:            30 * time.Second,
+	PushInterval:             10 * time.Second,
+	TimestampMonotonicPolicy: "sort",
+}
+
+// Example Config struct that sends basic auth credentials over plaintext http.
+var exampleInsecureCredentialsConfig = cortex.Config{
+	Endpoint:      "http://cortex:9009/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	BasicAuth: map[string]string{
+		"username": "user",
+		"password": "password",
+	},
+}
+
+// Example Config struct that explicitly allows sending credentials over plaintext http.
+var exampleAllowInsecureConfig = cortex.Config{
+	Endpoint:      "http://cortex:9009/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	BearerToken:   "bearer_token",
+	AllowInsecure: true,
+}
+
+// Example Config struct with a relabel config that uses an unsupported action.
+var exampleInvalidRelabelConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	RelabelConfigs: []cortex.RelabelConfig{
+		{Action: "hashmod"},
+	},
+}
+
+// Example Config struct with an unsupported duplicate scope policy.
+var exampleInvalidDuplicateScopePolicyConfig = cortex.Config{
+	Endpoint:             "/api/prom/push",
+	Name:                 "Config",
+	RemoteTimeout:        30 * time.Second,
+	PushInterval:         10 * time.Second,
+	DuplicateScopePolicy: "sum",
+}
+
+// Example Config struct with an unsupported temporality mismatch policy.
+var exampleInvalidTemporalityMismatchPolicyConfig = cortex.Config{
+	Endpoint:                  "/api/prom/push",
+	Name:                      "Config",
+	RemoteTimeout:             30 * time.Second,
+	PushInterval:              10 * time.Second,
+	TemporalityMismatchPolicy: "delta",
+}
+
+// Example Config struct with an unsupported partial collection policy.
+var exampleInvalidPartialCollectionPolicyConfig = cortex.Config{
+	Endpoint:                "/api/prom/push",
+	Name:                    "Config",
+	RemoteTimeout:           30 * time.Second,
+	PushInterval:            10 * time.Second,
+	PartialCollectionPolicy: "retry",
+}
+
+// Example Config struct with a metric name schema that is not a valid regular expression.
+var exampleInvalidMetricNameSchemaConfig = cortex.Config{
+	Endpoint:         "/api/prom/push",
+	Name:             "Config",
+	RemoteTimeout:    30 * time.Second,
+	PushInterval:     10 * time.Second,
+	MetricNameSchema: "myapp_(",
+}
+
+// Example Config struct with a metric name schema but no metric name schema policy.
+var exampleNoMetricNameSchemaPolicyConfig = cortex.Config{
+	Endpoint:         "/api/prom/push",
+	Name:             "Config",
+	RemoteTimeout:    30 * time.Second,
+	PushInterval:     10 * time.Second,
+	MetricNameSchema: "myapp_.*",
+}
+
+// Example Config struct with the default metric name schema policy.
+var validatedMetricNameSchemaPolicyConfig = cortex.Config{
+	Endpoint:               "/api/prom/push",
+	Name:                   "Config",
+	RemoteTimeout:          30 * time.Second,
+	PushInterval:           10 * time.Second,
+	MetricNameSchema:       "myapp_.*",
+	MetricNameSchemaPolicy: "drop",
+}
+
+var exampleInvalidTenantRateLimitConfig = cortex.Config{
+	Endpoint:            "/api/prom/push",
+	Name:                "Config",
+	RemoteTimeout:       30 * time.Second,
+	PushInterval:        10 * time.Second,
+	PerTenantRateLimits: map[string]float64{"team-a": 0},
+}
+
+var exampleInvalidSanitizationCollisionPolicyConfig = cortex.Config{
+	Endpoint:                    "/api/prom/push",
+	Name:                        "Config",
+	RemoteTimeout:               30 * time.Second,
+	PushInterval:                10 * time.Second,
+	SanitizationCollisionPolicy: "rename",
+}
+
+var exampleInvalidDownsampleConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	Downsample:    map[string]int{"metric_name": 0},
+}
+
+var exampleInvalidBodyStrategyConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	BodyStrategy:  "mmap",
+}
+
+var exampleInvalidOAuth2Config = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	OAuth2: map[string]string{
+		"client_id": "client",
+		"token_url": "https://auth.example.com/token",
+	},
+}
+
+var exampleConflictingOAuth2Config = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	BearerToken:   "token",
+	OAuth2: map[string]string{
+		"client_id":     "client",
+		"client_secret": "secret",
+		"token_url":     "https://auth.example.com/token",
+	},
+}
+
+var exampleInvalidValidationFailureModeConfig = cortex.Config{
+	Endpoint:              "/api/prom/push",
+	Name:                  "Config",
+	RemoteTimeout:         30 * time.Second,
+	PushInterval:          10 * time.Second,
+	ValidationFailureMode: "strict",
+}
+
+var exampleInvalidCompressionConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	Compression:   "zstd",
+}
+
+var exampleInvalidTenantShardingConfig = cortex.Config{
+	Endpoint:       "/api/prom/push",
+	Name:           "Config",
+	RemoteTimeout:  30 * time.Second,
+	PushInterval:   10 * time.Second,
+	TenantSharding: &cortex.ShardingConfig{Prefix: "team", Shards: 0},
+}
+
+var exampleNoWALDirectoryConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	WALConfig:     &cortex.WALConfig{Enabled: true},
+}
+
+var exampleInvalidAuthCacheSkewConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	AuthCacheSkew: -time.Second,
+}
+
+var exampleInvalidCrossScopeNameConflictPolicyConfig = cortex.Config{
+	Endpoint:                     "/api/prom/push",
+	Name:                         "Config",
+	RemoteTimeout:                30 * time.Second,
+	PushInterval:                 10 * time.Second,
+	CrossScopeNameConflictPolicy: "rename",
+}
+
+var exampleInvalidLatencyWindowConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	LatencyWindow: -1,
+}
+
+var exampleInvalidConnectionTimeoutConfig = cortex.Config{
+	Endpoint:            "/api/prom/push",
+	Name:                "Config",
+	RemoteTimeout:       30 * time.Second,
+	PushInterval:        10 * time.Second,
+	TLSHandshakeTimeout: -time.Second,
+}
+
+var exampleInvalidDrainTimeoutConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	DrainTimeout:  -time.Second,
+}
+
+var exampleInvalidHistogramBucketPolicyConfig = cortex.Config{
+	Endpoint:              "/api/prom/push",
+	Name:                  "Config",
+	RemoteTimeout:         30 * time.Second,
+	PushInterval:          10 * time.Second,
+	HistogramBucketPolicy: "clamp",
+}
+
+var exampleInvalidMetricNameFilterConfig = cortex.Config{
+	Endpoint:           "/api/prom/push",
+	Name:               "Config",
+	RemoteTimeout:      30 * time.Second,
+	PushInterval:       10 * time.Second,
+	MetricNameDenylist: []string{"runtime_.*", "http_(requests"},
+}
+
+var exampleInvalidWALMaxSegmentsConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	WALConfig:     &cortex.WALConfig{Enabled: true, Directory: "wal", MaxSegments: -1},
+}
+
+var exampleConflictingCAConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	TLSConfig: map[string]string{
+		"ca":                   "-----BEGIN CERTIFICATE-----",
+		"ca_file":              "ca_cert.pem",
+		"insecure_skip_verify": "false",
+	},
+}
+
+var exampleCertFileWithoutKeyFileConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	TLSConfig: map[string]string{
+		"cert_file":            "client_cert.pem",
+		"insecure_skip_verify": "false",
+	},
+}
+
+var exampleKeyFileWithoutCertFileConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	TLSConfig: map[string]string{
+		"key_file":             "client_key.pem",
+		"insecure_skip_verify": "false",
+	},
+}
+
+var exampleServerNameWithInsecureSkipVerifyConfig = cortex.Config{
+	Endpoint:      "/api/prom/push",
+	Name:          "Config",
+	RemoteTimeout: 30 * time.Second,
+	PushInterval:  10 * time.Second,
+	TLSConfig: map[string]string{
+		"server_name":          "cortex.example.com",
+		"insecure_skip_verify": "1",
+	},
+}
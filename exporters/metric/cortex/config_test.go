@@ -16,6 +16,7 @@ package cortex_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -79,6 +80,234 @@ func TestValidate(t *testing.T) {
 			expectedConfig: nil,
 			expectedError:  cortex.ErrConflictingAuthorization,
 		},
+		{
+			testName:       "Config with Content-Encoding Header",
+			config:         &exampleContentEncodingConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrConflictingContentEncoding,
+		},
+		{
+			testName:       "Config with Invalid In-Flight Policy",
+			config:         &exampleInvalidInFlightPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidInFlightPolicy,
+		},
+		{
+			testName:       "Config with Invalid Shard Label",
+			config:         &exampleInvalidShardLabelConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidShardLabel,
+		},
+		{
+			testName:       "Config with Invalid Label Precedence",
+			config:         &exampleInvalidLabelPrecedenceConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidLabelPrecedence,
+		},
+		{
+			testName:       "Config with Invalid Label Name Length Policy",
+			config:         &exampleInvalidLabelNameLengthPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidLabelNameLengthPolicy,
+		},
+		{
+			testName:       "Config with Conflicting Dedup Settings",
+			config:         &exampleConflictingDedupConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrConflictingDedup,
+		},
+		{
+			testName:       "Config with Invalid Timestamp Monotonic Policy",
+			config:         &exampleInvalidTimestampMonotonicPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidTimestampMonotonicPolicy,
+		},
+		{
+			testName:       "Config with Credentials over HTTP",
+			config:         &exampleInsecureCredentialsConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInsecureCredentials,
+		},
+		{
+			testName:       "Config with Credentials over HTTP and AllowInsecure",
+			config:         &exampleAllowInsecureConfig,
+			expectedConfig: &exampleAllowInsecureConfig,
+			expectedError:  nil,
+		},
+		{
+			testName:       "Config with Invalid Relabel Config",
+			config:         &exampleInvalidRelabelConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidRelabelConfig,
+		},
+		{
+			testName:       "Config with Invalid Duplicate Scope Policy",
+			config:         &exampleInvalidDuplicateScopePolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidDuplicateScopePolicy,
+		},
+		{
+			testName:       "Config with Invalid Temporality Mismatch Policy",
+			config:         &exampleInvalidTemporalityMismatchPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidTemporalityMismatchPolicy,
+		},
+		{
+			testName:       "Config with Invalid Partial Collection Policy",
+			config:         &exampleInvalidPartialCollectionPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidPartialCollectionPolicy,
+		},
+		{
+			testName:       "Config with Invalid Metric Name Schema",
+			config:         &exampleInvalidMetricNameSchemaConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidMetricNameSchema,
+		},
+		{
+			testName:       "Config with Invalid Tenant Rate Limit",
+			config:         &exampleInvalidTenantRateLimitConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidTenantRateLimit,
+		},
+		{
+			testName:       "Config with Invalid Sanitization Collision Policy",
+			config:         &exampleInvalidSanitizationCollisionPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidSanitizationCollisionPolicy,
+		},
+		{
+			testName:       "Config with Invalid Downsample Rate",
+			config:         &exampleInvalidDownsampleConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidDownsample,
+		},
+		{
+			testName:       "Config with Invalid Body Strategy",
+			config:         &exampleInvalidBodyStrategyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidBodyStrategy,
+		},
+		{
+			testName:       "Config with Invalid OAuth2 Config",
+			config:         &exampleInvalidOAuth2Config,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidOAuth2Config,
+		},
+		{
+			testName:       "Config with both OAuth2 and Bearer Token",
+			config:         &exampleConflictingOAuth2Config,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrConflictingAuthorization,
+		},
+		{
+			testName:       "Config with Invalid Validation Failure Mode",
+			config:         &exampleInvalidValidationFailureModeConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidValidationFailureMode,
+		},
+		{
+			testName:       "Config with Invalid Compression",
+			config:         &exampleInvalidCompressionConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidCompression,
+		},
+		{
+			testName:       "Config with Invalid Tenant Sharding",
+			config:         &exampleInvalidTenantShardingConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidTenantSharding,
+		},
+		{
+			testName:       "Config with no WAL Directory",
+			config:         &exampleNoWALDirectoryConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrNoWALDirectory,
+		},
+		{
+			testName:       "Config with Invalid Auth Cache Skew",
+			config:         &exampleInvalidAuthCacheSkewConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidAuthCacheSkew,
+		},
+		{
+			testName:       "Config with Invalid Cross-Scope Name Conflict Policy",
+			config:         &exampleInvalidCrossScopeNameConflictPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidCrossScopeNameConflictPolicy,
+		},
+		{
+			testName:       "Config with Invalid Latency Window",
+			config:         &exampleInvalidLatencyWindowConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidLatencyWindow,
+		},
+		{
+			testName:       "Config with Invalid Connection Timeout",
+			config:         &exampleInvalidConnectionTimeoutConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidConnectionTimeout,
+		},
+		{
+			testName:       "Config with Invalid Drain Timeout",
+			config:         &exampleInvalidDrainTimeoutConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidDrainTimeout,
+		},
+		{
+			testName:       "Config with Invalid Histogram Bucket Policy",
+			config:         &exampleInvalidHistogramBucketPolicyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidHistogramBucketPolicy,
+		},
+		{
+			testName:       "Config with Invalid Metric Name Filter",
+			config:         &exampleInvalidMetricNameFilterConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidMetricNameFilter,
+		},
+		{
+			testName:       "Config with Invalid WAL Max Segments",
+			config:         &exampleInvalidWALMaxSegmentsConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrInvalidWALMaxSegments,
+		},
+		{
+			testName:       "Config with Inline CA and CA File",
+			config:         &exampleConflictingCAConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrConflictingCA,
+		},
+		{
+			testName:       "Config with Certificate File without Key File",
+			config:         &exampleCertFileWithoutKeyFileConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrIncompleteClientCertificate,
+		},
+		{
+			testName:       "Config with Key File without Certificate File",
+			config:         &exampleKeyFileWithoutCertFileConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrIncompleteClientCertificate,
+		},
+		{
+			testName:       "Config with Server Name and Insecure Skip Verify",
+			config:         &exampleServerNameWithInsecureSkipVerifyConfig,
+			expectedConfig: nil,
+			expectedError:  cortex.ErrServerNameWithInsecureSkipVerify,
+		},
+		{
+			testName:       "Config with no Metric Name Schema Policy",
+			config:         &exampleNoMetricNameSchemaPolicyConfig,
+			expectedConfig: &validatedMetricNameSchemaPolicyConfig,
+			expectedError:  nil,
+		},
+		{
+			testName:       "Config with no In-Flight Policy",
+			config:         &exampleNoInFlightPolicyConfig,
+			expectedConfig: &validatedInFlightPolicyConfig,
+			expectedError:  nil,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.testName, func(t *testing.T) {
@@ -90,3 +319,86 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateEndpoint checks whether Validate accepts absolute URLs, Unix domain socket
+// endpoints, and paths, and rejects endpoints without a scheme or a host.
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint      string
+		expectedError error
+	}{
+		{"", nil},
+		{"/api/prom/push", nil},
+		{"http://localhost:9009/api/prom/push", nil},
+		{"https://cortex.example.com/push", nil},
+		{"unix:///var/run/cortex.sock:/api/prom/push", nil},
+		{"cortex.example.com/push", cortex.ErrInvalidEndpoint},
+		{"localhost:9009/api/prom/push", cortex.ErrInvalidEndpoint},
+		{"http:///api/prom/push", cortex.ErrInvalidEndpoint},
+		{"http://cortex\x7f/push", cortex.ErrInvalidEndpoint},
+	}
+
+	for _, test := range tests {
+		t.Run(test.endpoint, func(t *testing.T) {
+			config := cortex.Config{Endpoint: test.endpoint}
+			require.Equal(t, test.expectedError, config.Validate())
+		})
+	}
+}
+
+// TestEffective checks whether the effective Config holds the default values and
+// redacted secrets without changing the Config it was created from.
+func TestEffective(t *testing.T) {
+	config := cortex.Config{
+		Endpoint: "https://cortex:9009/api/prom/push",
+		BasicAuth: map[string]string{
+			"username": "user",
+			"password": "password",
+		},
+		Headers: map[string]string{
+			"authorization": "Bearer token",
+			"X-Scope-OrgID": "tenant",
+		},
+		RetryOnDialError: &cortex.RetryConfig{},
+		RetryConfig:      &cortex.RetryConfig{MaxRetries: 5},
+	}
+
+	effective := config.Effective()
+	require.Equal(t, 30*time.Second, effective.RemoteTimeout)
+	require.Equal(t, 10*time.Second, effective.PushInterval)
+	require.Equal(t, 3, effective.RetryOnDialError.MaxRetries)
+	require.Equal(t, 5, effective.RetryConfig.MaxRetries)
+	require.Equal(t, 100*time.Millisecond, effective.RetryConfig.InitialInterval)
+	require.Equal(t, map[string]string{
+		"username": "user",
+		"password": "<redacted>",
+	}, effective.BasicAuth)
+	require.Equal(t, map[string]string{
+		"authorization": "<redacted>",
+		"X-Scope-OrgID": "tenant",
+	}, effective.Headers)
+
+	// The Config the effective Config was created from is unchanged.
+	require.Equal(t, time.Duration(0), config.RemoteTimeout)
+	require.Equal(t, 0, config.RetryOnDialError.MaxRetries)
+	require.Equal(t, time.Duration(0), config.RetryConfig.InitialInterval)
+	require.Equal(t, "password", config.BasicAuth["password"])
+	require.Equal(t, "Bearer token", config.Headers["authorization"])
+}
+
+// TestEffectiveOAuth2 checks whether the OAuth2 client secret is redacted.
+func TestEffectiveOAuth2(t *testing.T) {
+	config := cortex.Config{
+		Endpoint: "https://cortex:9009/api/prom/push",
+		OAuth2: map[string]string{
+			"client_id":     "client",
+			"client_secret": "secret",
+			"token_url":     "https://auth.example.com/token",
+		},
+	}
+
+	effective := config.Effective()
+	require.Equal(t, "client", effective.OAuth2["client_id"])
+	require.Equal(t, "<redacted>", effective.OAuth2["client_secret"])
+	require.Equal(t, "secret", config.OAuth2["client_secret"])
+}
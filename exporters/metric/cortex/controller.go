@@ -0,0 +1,185 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/api/global"
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/api/metric/registry"
+	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/controller/push"
+	controllerTime "go.opentelemetry.io/otel/sdk/metric/controller/time"
+	"go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+)
+
+// Controller collects metrics and pushes them to Cortex with an Exporter every push
+// period, like the push Controller NewExportPipeline returns. Unlike that Controller, it
+// can also be flushed on demand, e.g. to push the metrics accumulated since the last
+// push when the process shuts down.
+type Controller struct {
+	accumulator *sdk.Accumulator
+	processor   *basic.Processor
+	provider    *registry.Provider
+	exporter    *Exporter
+
+	period  time.Duration
+	timeout time.Duration
+	clock   controllerTime.Clock
+
+	// flushing holds a token while metrics are collected and pushed, so that periodic
+	// pushes and flushes never run at the same time. Unlike a mutex, waiting for it can
+	// be canceled with a context.
+	flushing chan struct{}
+
+	// lock protects the state of the background goroutine that pushes periodically.
+	lock    sync.Mutex
+	ticker  controllerTime.Ticker
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// NewController validates the Config struct and creates a Controller with an Exporter
+// for it. The push period is the PushInterval in the Config unless it is set with
+// push.WithPeriod. The Controller has to be started with Start.
+func NewController(config Config, options ...push.Option) (*Controller, error) {
+	exporter, err := NewRawExporter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	pushConfig := push.Config{Period: exporter.config.PushInterval}
+	for _, option := range options {
+		option.Apply(&pushConfig)
+	}
+	if pushConfig.Timeout == 0 {
+		pushConfig.Timeout = pushConfig.Period
+	}
+
+	processor := basic.New(simple.NewWithHistogramDistribution(config.HistogramBoundaries), exporter)
+	accumulator := sdk.NewAccumulator(processor, sdk.WithResource(pushConfig.Resource))
+	c := &Controller{
+		accumulator: accumulator,
+		processor:   processor,
+		provider:    registry.NewProvider(accumulator),
+		exporter:    exporter,
+		period:      pushConfig.Period,
+		timeout:     pushConfig.Timeout,
+		clock:       controllerTime.RealClock{},
+		flushing:    make(chan struct{}, 1),
+	}
+	exporter.pipelineProvider = c.provider
+	exporter.replayPendingWAL()
+
+	// The first push happens right away unless PushOnStart is disabled.
+	if config.PushOnStart == nil || *config.PushOnStart {
+		c.clock = immediateClock{c.clock}
+	}
+	return c, nil
+}
+
+// Provider returns the Provider the metrics pushed by the Controller are recorded with.
+func (c *Controller) Provider() apimetric.Provider {
+	return c.provider
+}
+
+// Start starts pushing metrics every push period in the background. It does nothing if
+// the Controller is already started or was stopped.
+func (c *Controller) Start() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.ticker != nil || c.stopped {
+		return
+	}
+	c.ticker = c.clock.Ticker(c.period)
+	c.stop = make(chan struct{})
+	c.wg.Add(1)
+	go c.run(c.ticker, c.stop)
+}
+
+// Stop stops pushing metrics in the background, pushes them one last time, and then
+// stops the Exporter, which drains its WAL and closes the idle connections of its http
+// Client. The final push is bounded by the deadline of ctx and the push timeout. Stop
+// returns the error of the final push, or else the error of stopping the Exporter. Only
+// the first call does anything; later calls return nil.
+func (c *Controller) Stop(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stopped {
+		return nil
+	}
+	c.stopped = true
+	if c.ticker != nil {
+		close(c.stop)
+		c.wg.Wait()
+		c.ticker.Stop()
+		c.ticker = nil
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	flushErr := c.Flush(flushCtx)
+	if err := c.exporter.Stop(ctx); flushErr == nil {
+		return err
+	}
+	return flushErr
+}
+
+// run pushes metrics on every tick until stop is closed.
+func (c *Controller) run(ticker controllerTime.Ticker, stop chan struct{}) {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C():
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			if err := c.Flush(ctx); err != nil {
+				global.Handle(err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Flush collects the metrics accumulated since the last push and pushes them right away.
+// It is safe to call concurrently with the periodic pushes, and waits for a push in
+// progress to finish first. Both the wait and the push are bounded by the deadline of
+// ctx.
+func (c *Controller) Flush(ctx context.Context) error {
+	select {
+	case c.flushing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-c.flushing }()
+
+	c.processor.Lock()
+	defer c.processor.Unlock()
+
+	c.processor.StartCollection()
+	c.accumulator.Collect(ctx)
+	if err := c.processor.FinishCollection(); err != nil {
+		return err
+	}
+	return c.exporter.Export(ctx, c.processor.CheckpointSet())
+}
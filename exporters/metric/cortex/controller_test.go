@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/sdk/metric/controller/push"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// valueServer is a Cortex endpoint that keeps the last value it received for every
+// series name.
+type valueServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests int
+	values   map[string]float64
+}
+
+// newValueServer starts a valueServer.
+func newValueServer(t *testing.T) *valueServer {
+	s := &valueServer{values: map[string]float64{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.requests++
+		for name, value := range timeSeriesValues(writeRequest.Timeseries) {
+			s.values[name] = value
+		}
+	}))
+	return s
+}
+
+// received returns the number of requests and the last value received for a series.
+func (s *valueServer) received(name string) (int, float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests, s.values[name]
+}
+
+// TestControllerFlush checks whether Flush pushes the metrics accumulated since the last
+// push right away.
+func TestControllerFlush(t *testing.T) {
+	server := newValueServer(t)
+	defer server.Close()
+
+	pushOnStart := false
+	controller, err := NewController(Config{Endpoint: server.URL, PushOnStart: &pushOnStart}, push.WithPeriod(time.Hour))
+	require.Nil(t, err)
+	controller.Start()
+	defer func() { require.Nil(t, controller.Stop(context.Background())) }()
+
+	counter := apimetric.Must(controller.Provider().Meter("test")).NewInt64Counter("metric_name")
+	counter.Add(context.Background(), 5)
+	require.Nil(t, controller.Flush(context.Background()))
+	requests, value := server.received("metric_name")
+	require.Equal(t, 1, requests)
+	require.Equal(t, float64(5), value)
+
+	counter.Add(context.Background(), 2)
+	require.Nil(t, controller.Flush(context.Background()))
+	requests, value = server.received("metric_name")
+	require.Equal(t, 2, requests)
+	require.Equal(t, float64(7), value)
+}
+
+// TestControllerFlushConcurrently checks whether flushes wait for each other and whether
+// waiting is bounded by the deadline of the context.
+func TestControllerFlushConcurrently(t *testing.T) {
+	server := newValueServer(t)
+	defer server.Close()
+
+	controller, err := NewController(Config{Endpoint: server.URL})
+	require.Nil(t, err)
+	counter := apimetric.Must(controller.Provider().Meter("test")).NewInt64Counter("metric_name")
+	counter.Add(context.Background(), 1)
+
+	// A push in progress makes the flush wait until the deadline.
+	controller.flushing <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, controller.Flush(ctx))
+	<-controller.flushing
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, controller.Flush(context.Background()))
+		}()
+	}
+	wg.Wait()
+	requests, value := server.received("metric_name")
+	require.Equal(t, 5, requests)
+	require.Equal(t, float64(1), value)
+}
+
+// TestControllerPushPeriod checks whether the Controller pushes every PushInterval
+// right after it is started and once more when it is stopped.
+func TestControllerPushPeriod(t *testing.T) {
+	server := newValueServer(t)
+	defer server.Close()
+
+	controller, err := NewController(Config{Endpoint: server.URL, PushInterval: 20 * time.Millisecond})
+	require.Nil(t, err)
+	counter := apimetric.Must(controller.Provider().Meter("test")).NewInt64Counter("metric_name")
+	counter.Add(context.Background(), 3)
+
+	controller.Start()
+	controller.Start()
+	require.Eventually(t, func() bool {
+		requests, _ := server.received("metric_name")
+		return requests >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Nil(t, controller.Stop(context.Background()))
+	requests, value := server.received("metric_name")
+	require.Equal(t, float64(3), value)
+	time.Sleep(50 * time.Millisecond)
+	stoppedRequests, _ := server.received("metric_name")
+	require.Equal(t, requests, stoppedRequests)
+}
+
+// closeCountingTransport is an http Transport that counts how often its idle connections
+// are closed.
+type closeCountingTransport struct {
+	*http.Transport
+	closed int
+}
+
+// CloseIdleConnections counts the call and closes the idle connections.
+func (t *closeCountingTransport) CloseIdleConnections() {
+	t.closed++
+	t.Transport.CloseIdleConnections()
+}
+
+// TestControllerStop checks whether Stop pushes one last time, returns the error of that
+// push, closes idle connections, and does nothing when it is called again.
+func TestControllerStop(t *testing.T) {
+	tests := []struct {
+		testName   string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			testName:   "Final push succeeds",
+			statusCode: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			testName:   "Final push fails",
+			statusCode: http.StatusBadRequest,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			requests := 0
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				requests++
+				rw.WriteHeader(test.statusCode)
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			transport := &closeCountingTransport{Transport: &http.Transport{}}
+			pushOnStart := false
+			controller, err := NewController(Config{
+				Endpoint:    server.URL,
+				Client:      &http.Client{Transport: transport},
+				PushOnStart: &pushOnStart,
+			}, push.WithPeriod(time.Hour))
+			require.Nil(t, err)
+			controller.Start()
+
+			counter := apimetric.Must(controller.Provider().Meter("test")).NewInt64Counter("metric_name")
+			counter.Add(context.Background(), 1)
+
+			err = controller.Stop(context.Background())
+			require.Equal(t, test.wantErr, err != nil)
+			require.Equal(t, 1, requests)
+			require.Equal(t, 1, transport.closed)
+
+			require.Nil(t, controller.Stop(context.Background()))
+			controller.Start()
+			require.Equal(t, 1, requests)
+			require.Equal(t, 1, transport.closed)
+		})
+	}
+}
@@ -17,13 +17,21 @@ package cortex
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/golang/snappy"
 	"github.com/prometheus/prometheus/prompb"
 
 	"go.opentelemetry.io/otel/api/global"
@@ -33,12 +41,113 @@ import (
 	export "go.opentelemetry.io/otel/sdk/export/metric"
 	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
 	"go.opentelemetry.io/otel/sdk/metric/controller/push"
+	controllerTime "go.opentelemetry.io/otel/sdk/metric/controller/time"
 	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
 )
 
 // Exporter forwards metrics to a Cortex instance
 type Exporter struct {
+	// pushSequence is the sequence number of the last push. It is only used when
+	// EmitPushSequence is set. It is the first field so that it is 64-bit aligned for
+	// atomic operations on 32-bit platforms.
+	pushSequence uint64
+
 	config Config
+
+	// lock protects the state the Exporter keeps between pushes.
+	lock sync.Mutex
+
+	// lastSent holds the last sample sent for each series. It is only used when
+	// DedupUnchangedInterval is set.
+	lastSent map[string]prompb.Sample
+
+	// inFlight holds a token for every request in flight. It is only used when
+	// MaxInFlightRequests is set.
+	inFlight     chan struct{}
+	inFlightOnce sync.Once
+
+	// selfMetrics holds the instruments the Exporter reports on itself with.
+	selfMetrics     *selfMetrics
+	selfMetricsOnce sync.Once
+
+	// pipelineProvider is the Provider of the push Controller created for the Exporter
+	// by NewExportPipeline, which self-metrics are never recorded with.
+	pipelineProvider apimetric.Provider
+
+	// sumStates holds the start time and total of every monotonic sum seen within
+	// seriesStateTTL. It is only used when TemporalityMismatchPolicy is set.
+	sumStates map[string]sumState
+
+	// presentSeries holds the labels of the series of the last push by their series key.
+	// It is only used when EmitGapMarkers or MarkStaleOnResourceChange is set.
+	presentSeries map[string][]*prompb.Label
+
+	// resolver looks up the SRV records of the endpoint when UseSRVDiscovery is set. The
+	// default resolver is used when it is nil.
+	resolver srvResolver
+
+	// srvTargets holds the SRV records of the endpoint, which were looked up at
+	// srvRefreshed.
+	srvTargets   []*net.SRV
+	srvRefreshed time.Time
+
+	// downsampleCounts holds how many collections of a record were seen since it was last
+	// sent, for every record seen within seriesStateTTL. It is only used when Downsample
+	// is set.
+	downsampleCounts map[string]downsampleState
+
+	// certExpiryChecked is when the client certificate was last checked for expiry. It is
+	// only used when CertExpiryWarnWindow is set.
+	certExpiryChecked time.Time
+
+	// pushResources holds the resources of the records converted since the last push, and
+	// lastResources the ones of the last push. They are only used when resource changes
+	// are detected.
+	pushResources map[string]bool
+	lastResources string
+
+	// walLock serializes the pushes that use the WAL, so that a pending segment is never
+	// sent by two pushes at once. walLastSegment is the ID of the last segment written
+	// and walTruncated is when acknowledged segments were last deleted.
+	walLock        sync.Mutex
+	walLastSegment int64
+	walTruncated   time.Time
+
+	// paused is set while the Exporter is paused with Pause.
+	paused bool
+
+	// latencies holds the durations of the last LatencyWindow pushes. Once it is full,
+	// latencyNext is the index of the oldest one.
+	latencies   []time.Duration
+	latencyNext int
+
+	// tenantLimiters holds a token bucket for every tenant in PerTenantRateLimits that
+	// requests were sent for.
+	tenantLimiters map[string]*tokenBucket
+
+	// rateLimitClock is the clock of the tenantLimiters. The wall clock is used when it is
+	// nil.
+	rateLimitClock waitClock
+
+	// oauth2AccessToken holds the token fetched from the OAuth2 token endpoint, which
+	// expires at oauth2Expiry. They are protected by oauth2Lock rather than lock so that
+	// fetching a token does not block the rest of the Exporter.
+	oauth2AccessToken string
+	oauth2Expiry      time.Time
+	oauth2Lock        sync.Mutex
+
+	// metricNameRegex holds the compiled MetricNameSchema.
+	metricNameRegex      *regexp.Regexp
+	metricNameSchemaOnce sync.Once
+
+	// relabelRegexs holds the compiled regular expressions of the RelabelConfigs.
+	relabelRegexs []*regexp.Regexp
+	relabelOnce   sync.Once
+
+	// allowRegexs and denyRegexs hold the compiled MetricNameAllowlist and
+	// MetricNameDenylist.
+	allowRegexs []*regexp.Regexp
+	denyRegexs  []*regexp.Regexp
 }
 
 // ExportKindFor returns CumulativeExporter so the Processor correctly aggregates data
@@ -46,29 +155,195 @@ func (e *Exporter) ExportKindFor(*apimetric.Descriptor, aggregation.Kind) metric
 	return metric.CumulativeExporter
 }
 
-// Export forwards metrics to Cortex from the SDK
-func (e *Exporter) Export(_ context.Context, checkpointSet metric.CheckpointSet) error {
+// ExportResult describes the outcome of a push to Cortex.
+type ExportResult struct {
+	// SeriesSent is the number of TimeSeries in the requests.
+	SeriesSent int
+
+	// BytesSent is the total size of the compressed request bodies.
+	BytesSent int
+
+	// UncompressedBytes is the total size of the request bodies before compression.
+	UncompressedBytes int
+
+	// Retries is the number of times requests were retried.
+	Retries int
+
+	// StatusCode is the status code of the last response, or 0 if no response was
+	// received.
+	StatusCode int
+}
+
+// Export forwards metrics to Cortex from the SDK. Every request is bounded by both the
+// deadline of ctx and RemoteTimeout, whichever ends first.
+func (e *Exporter) Export(ctx context.Context, checkpointSet metric.CheckpointSet) error {
+	_, err := e.ExportWithResult(ctx, checkpointSet)
+	return err
+}
+
+// ExportWithResult forwards metrics to Cortex like Export and additionally returns a
+// description of the outcome of the push. The result is filled in as far as the push
+// got, even when an error is returned.
+func (e *Exporter) ExportWithResult(ctx context.Context, checkpointSet metric.CheckpointSet) (ExportResult, error) {
+	start := time.Now()
+	result, err := e.export(ctx, checkpointSet)
+	duration := time.Since(start)
+	e.recordLatency(duration)
+	e.recordPush(duration, err)
+	e.publishEvent(result, duration, err)
+	return result, err
+}
+
+// export converts the records of a CheckpointSet and sends them to Cortex.
+func (e *Exporter) export(ctx context.Context, checkpointSet metric.CheckpointSet) (ExportResult, error) {
+	var result ExportResult
+
+	if e.Paused() {
+		e.addPausedPush()
+		return result, nil
+	}
+
+	// The deadline bounds the whole push, including retries and the requests of a split
+	// push, so that a push never takes longer than MaxPushDuration.
+	if e.config.MaxPushDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.MaxPushDuration)
+		defer cancel()
+	}
+
+	// TimeSeries returned along with an error were collected before the collection
+	// failed. They are sent before the error is returned.
 	timeseries, err := e.ConvertToTimeSeries(checkpointSet)
-	if err != nil {
-		return err
+	if err != nil && timeseries == nil {
+		return result, err
+	}
+	collectError := err
+	if e.config.TemporalityMismatchPolicy != "" {
+		e.evictSumStates()
+	}
+	if len(e.config.Downsample) > 0 {
+		e.evictDownsampleCounts()
+	}
+	resourceChanged := false
+	if e.detectResourceChanges() {
+		resourceChanged = e.checkResourceChange()
+	}
+	if e.config.CertExpiryWarnWindow > 0 {
+		e.checkCertExpiry(time.Now())
+	}
+	if e.config.ReportTimestampSkew {
+		e.recordTimestampSkew(timeseries, time.Now())
+	}
+	if e.config.AlignTimestamps {
+		alignTimestamps(timeseries, e.config.PushInterval)
+	}
+	if e.config.MetricNameSchema != "" {
+		timeseries, err = e.checkMetricNames(timeseries)
+		if err != nil {
+			return result, err
+		}
+	}
+	if e.config.DuplicateScopePolicy != "" {
+		timeseries, err = e.checkDuplicateSeries(timeseries)
+		if err != nil {
+			return result, err
+		}
+	}
+	if e.config.TimestampMonotonicPolicy != "" {
+		if err := e.checkMonotonicTimestamps(timeseries); err != nil {
+			return result, err
+		}
+	}
+	if e.config.MaxSamplesPerSeries > 0 {
+		e.limitSamplesPerSeries(timeseries)
+	}
+	if e.config.MaxLabelNameLength > 0 {
+		e.limitLabelNameLength(timeseries)
 	}
+	if e.config.MaxSeriesBytes > 0 {
+		timeseries, err = e.limitSeriesBytes(timeseries)
+		if err != nil {
+			return result, err
+		}
+	}
+	if e.config.SanitizeLabelValues {
+		sanitizeLabelValues(timeseries)
+	}
+	// Markers are added before dedup so that the first sample after a gap is always sent.
+	if e.config.EmitGapMarkers || e.config.MarkStaleOnResourceChange {
+		timeseries = e.addGapMarkers(timeseries, time.Now(), e.config.EmitGapMarkers || resourceChanged)
+	}
+	var dedupKept map[string]prompb.Sample
+	if e.dedupInterval() > 0 {
+		timeseries, dedupKept = e.dedupUnchanged(timeseries)
+	}
+	if e.config.SeriesWarnThreshold > 0 {
+		e.warnSeriesCount(timeseries)
+	}
+	if e.config.MaxTotalSeries > 0 {
+		timeseries = e.limitTotalSeries(timeseries)
+	}
+	converted := len(timeseries)
+	if e.config.EmitBuildInfo {
+		timeseries = append(timeseries, buildInfoTimeSeries(time.Now()))
+	}
+	if e.config.EmitPushSequence {
+		timeseries = append(timeseries, e.pushSequenceTimeSeries(time.Now()))
+	}
+	if e.config.ExternalLabels != nil {
+		e.addExternalLabels(timeseries[converted:])
+	}
+	if e.config.ShardLabel != nil {
+		setLabel(timeseries, sanitize(e.config.ShardLabel["name"]), e.config.ShardLabel["value"])
+	}
+	// Labels added to the converted TimeSeries above are appended to their labels.
+	sortLabels(timeseries)
 
-	message, buildMessageErr := e.buildMessage(timeseries)
-	if buildMessageErr != nil {
-		return buildMessageErr
+	// A dry run stops right before anything is written to the WAL or sent to Cortex.
+	if e.config.DryRun {
+		if err := e.logDryRun(timeseries); err != nil {
+			return result, err
+		}
+		e.commitLastSent(dedupKept)
+		return result, collectError
 	}
 
-	request, buildRequestErr := e.buildRequest(message)
-	if buildRequestErr != nil {
-		return buildRequestErr
+	// Segments of earlier pushes that failed or were interrupted by a restart are sent
+	// before the new one. If they cannot be sent, the new one is only written to the
+	// WAL, since Cortex would reject the older samples as out of order once newer
+	// samples of the same series were accepted.
+	var segment string
+	if e.walEnabled() {
+		e.walLock.Lock()
+		defer e.walLock.Unlock()
+		replayErr := e.replayWAL(ctx)
+		if len(timeseries) > 0 {
+			if segment, err = e.appendWAL(timeseries); err != nil {
+				return result, err
+			}
+		}
+		if replayErr != nil {
+			return result, replayErr
+		}
 	}
 
-	sendRequestErr := e.sendRequest(request)
-	if sendRequestErr != nil {
-		return sendRequestErr
+	sendErr := e.sendTimeSeries(ctx, timeseries, &result)
+	if sendErr != nil {
+		return result, sendErr
 	}
+	e.commitLastSent(dedupKept)
+	e.recordCompressionRatio(result)
 
-	return nil
+	if segment != "" {
+		if err := ackWAL(segment); err != nil {
+			return result, err
+		}
+		if err := e.truncateWAL(time.Now()); err != nil {
+			e.logf("Could not truncate the WAL: %v", err)
+		}
+	}
+
+	return result, collectError
 }
 
 // NewRawExporter validates the Config struct and creates an Exporter with it.
@@ -79,112 +354,392 @@ func NewRawExporter(config Config) (*Exporter, error) {
 		return nil, err
 	}
 
-	exporter := Exporter{config}
+	exporter := Exporter{config: config}
+	if err := exporter.compileMetricNameFilters(); err != nil {
+		return nil, err
+	}
+	if config.CertExpiryWarnWindow > 0 {
+		exporter.checkCertExpiry(time.Now())
+	}
+	// A failed warm-up is only logged since the first push connects to Cortex as well.
+	if config.WarmUpConnection {
+		ctx, cancel := context.WithTimeout(context.Background(), exporter.warmUpTimeout())
+		err := exporter.warmUp(ctx)
+		cancel()
+		if err != nil {
+			exporter.logf("Connection warm-up failed: %v", err)
+		}
+	}
 	return &exporter, nil
 }
 
 // NewExportPipeline sets up a complete export pipeline with a push Controller and
 // Exporter.
 func NewExportPipeline(config Config, options ...push.Option) (*push.Controller, error) {
-	exporter, err := NewRawExporter(config)
+	pusher, err := newExportPipeline(config, options...)
 	if err != nil {
 		return nil, err
 	}
-
-	pusher := push.New(
-		simple.NewWithHistogramDistribution(config.HistogramBoundaries),
-		exporter,
-		options...,
-	)
 	pusher.Start()
 	return pusher, nil
 }
 
 // InstallNewPipeline registers a push Controller's Provider globally.
 func InstallNewPipeline(config Config, options ...push.Option) (*push.Controller, error) {
-	pusher, err := NewExportPipeline(config, options...)
+	pusher, err := newExportPipeline(config, options...)
 	if err != nil {
 		return nil, err
 	}
+	// The Provider is registered before the first push so that the Exporter finds its
+	// own Provider when it creates its self-metrics from the global one.
 	global.SetMeterProvider(pusher.Provider())
+	pusher.Start()
 	return pusher, nil
 }
 
+// newExportPipeline creates a push Controller and Exporter without starting the
+// Controller.
+func newExportPipeline(config Config, options ...push.Option) (*push.Controller, error) {
+	exporter, err := NewRawExporter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	pusher := push.New(
+		simple.NewWithHistogramDistribution(config.HistogramBoundaries),
+		exporter,
+		options...,
+	)
+	exporter.pipelineProvider = pusher.Provider()
+	exporter.replayPendingWAL()
+	// The first push happens right away unless PushOnStart is disabled.
+	if config.PushOnStart == nil || *config.PushOnStart {
+		pusher.SetClock(immediateClock{controllerTime.RealClock{}})
+	}
+	return pusher, nil
+}
+
+const (
+	// PartialCollectionPolicyAbort fails a push without sending anything when the
+	// records cannot all be collected from the CheckpointSet.
+	PartialCollectionPolicyAbort = "abort"
+
+	// PartialCollectionPolicySend sends the records collected before a collection error
+	// and then fails the push with the error.
+	PartialCollectionPolicySend = "send"
+)
+
 // ConvertToTimeSeries converts a CheckpointSet to a slice of TimeSeries pointers
 // Based on the aggregation type, ConvertToTimeSeries will call helper function like
-// convertFromSum to generate the correct number of TimeSeries.
+// convertFromSum to generate the correct number of TimeSeries. If the CheckpointSet
+// fails to iterate over its records and PartialCollectionPolicy is "send", the TimeSeries
+// converted until then are returned along with the error.
 func (e *Exporter) ConvertToTimeSeries(checkpointSet export.CheckpointSet) ([]*prompb.TimeSeries, error) {
-	var aggError error
+	if e.config.ConvertConcurrency > 1 {
+		return e.convertConcurrently(checkpointSet)
+	}
+
+	var aggError, convertError error
 	var timeSeries []*prompb.TimeSeries
+	merger := histogramMerger{exporter: e}
+	resolver := scopeConflictResolver{exporter: e}
 
 	// Iterate over each record in the checkpoint set and convert to TimeSeries
 	aggError = checkpointSet.ForEach(e, func(record metric.Record) error {
-		// Convert based on aggregation type
-		agg := record.Aggregation()
-
-		// The following section uses loose type checking to determine how to
-		// convert aggregations to timeseries. More "expensive" timeseries are
-		// checked first. For example, because a Distribution has a Sum value,
-		// we must check for Distribution first or else only the Sum would be
-		// converted and the other values like Quantiles would not be.
-		//
-		// See the Aggregator Kind for more information
-		// https://github.com/open-telemetry/opentelemetry-go/blob/master/sdk/export/metric/aggregation/aggregation.go#L123-L138
-		if histogram, ok := agg.(aggregation.Histogram); ok {
-			tSeries, err := convertFromHistogram(record, histogram)
-			if err != nil {
-				return err
-			}
+		tSeries, err := e.convertRecord(record)
+		if err == nil && e.config.CrossScopeNameConflictPolicy != "" {
+			tSeries, err = resolver.resolve(record, tSeries)
+		}
+		if err != nil {
+			convertError = err
+			return err
+		}
+		if e.config.MergeDuplicateHistograms {
+			timeSeries = merger.add(timeSeries, record, tSeries)
+		} else {
 			timeSeries = append(timeSeries, tSeries...)
-		} else if distribution, ok := agg.(aggregation.Distribution); ok && len(e.config.Quantiles) != 0 {
-			tSeries, err := convertFromDistribution(record, distribution, e.config.Quantiles)
-			if err != nil {
-				return err
+		}
+		return nil
+	})
+
+	// Check if error was returned in checkpointSet.ForEach(). Errors that did not occur
+	// during conversion occurred while collecting the records.
+	if aggError != nil {
+		if convertError == nil && e.config.PartialCollectionPolicy == PartialCollectionPolicySend {
+			return timeSeries, aggError
+		}
+		return nil, aggError
+	}
+
+	return timeSeries, nil
+}
+
+// convertConcurrently converts the records of a CheckpointSet on up to
+// ConvertConcurrency goroutines. The resulting TimeSeries are in the same order as if
+// the records were converted one after another.
+func (e *Exporter) convertConcurrently(checkpointSet export.CheckpointSet) ([]*prompb.TimeSeries, error) {
+	var records []metric.Record
+	collectError := checkpointSet.ForEach(e, func(record metric.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if collectError != nil && e.config.PartialCollectionPolicy != PartialCollectionPolicySend {
+		return nil, collectError
+	}
+
+	// Each worker writes to the indexes of the records it converts, so results do not
+	// need to be synchronized.
+	results := make([][]*prompb.TimeSeries, len(records))
+	errs := make([]error, len(records))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.config.ConvertConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index], errs[index] = e.convertRecord(records[index])
 			}
+		}()
+	}
+	for index := range records {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
 
+	var timeSeries []*prompb.TimeSeries
+	merger := histogramMerger{exporter: e}
+	resolver := scopeConflictResolver{exporter: e}
+	for index, tSeries := range results {
+		// Return the error of the first record that failed, like a serial conversion.
+		// CheckpointSets tolerate ErrNoData, so it is tolerated here as well.
+		if errs[index] != nil && !errors.Is(errs[index], aggregation.ErrNoData) {
+			return nil, errs[index]
+		}
+		// Conflicts are resolved in record order so that the same metric is kept first.
+		if e.config.CrossScopeNameConflictPolicy != "" {
+			var err error
+			if tSeries, err = resolver.resolve(records[index], tSeries); err != nil {
+				return nil, err
+			}
+		}
+		if e.config.MergeDuplicateHistograms {
+			timeSeries = merger.add(timeSeries, records[index], tSeries)
+		} else {
 			timeSeries = append(timeSeries, tSeries...)
-		} else if sum, ok := agg.(aggregation.Sum); ok {
-			tSeries, err := convertFromSum(record, sum)
-			if err != nil {
-				return err
+		}
+	}
+	return timeSeries, collectError
+}
+
+// convertRecord converts a single Record to TimeSeries based on its aggregation type.
+func (e *Exporter) convertRecord(record metric.Record) ([]*prompb.TimeSeries, error) {
+	if e.config.DropRuntimeMetrics && isRuntimeMetric(record.Descriptor().Name()) {
+		return nil, nil
+	}
+	if (e.config.MetricNameAllowlist != nil || e.config.MetricNameDenylist != nil) && !e.keepMetricName(sanitize(record.Descriptor().Name())) {
+		return nil, nil
+	}
+	if e.detectResourceChanges() {
+		e.noteResource(record.Resource())
+	}
+
+	var timeSeries []*prompb.TimeSeries
+	record = e.mergeLabels(record)
+	if e.config.CollapseAttributesToJSON {
+		record = collapseAttributes(record)
+	}
+	if e.config.SanitizationCollisionPolicy != "" {
+		var err error
+		if record, err = e.resolveLabelCollisions(record); err != nil {
+			return nil, err
+		}
+	}
+
+	// Convert based on aggregation type
+	agg := record.Aggregation()
+
+	// Whether the converted record is a counter, histogram, or summary. Only these
+	// are accompanied by a "_created" series.
+	var cumulative bool
+
+	// The following section uses loose type checking to determine how to
+	// convert aggregations to timeseries. More "expensive" timeseries are
+	// checked first. For example, because a Distribution has a Sum value,
+	// we must check for Distribution first or else only the Sum would be
+	// converted and the other values like Quantiles would not be.
+	//
+	// See the Aggregator Kind for more information
+	// https://github.com/open-telemetry/opentelemetry-go/blob/master/sdk/export/metric/aggregation/aggregation.go#L123-L138
+	if histogram, ok := agg.(aggregation.Histogram); ok {
+		e.warnReservedLabels(record, "le")
+		if e.config.HistogramBucketPolicy != "" {
+			var keep bool
+			var err error
+			if histogram, keep, err = e.checkHistogramBuckets(record, histogram); err != nil || !keep {
+				return nil, err
 			}
+		}
+		tSeries, err := convertFromHistogram(record, histogram)
+		if err != nil {
+			return nil, err
+		}
+		timeSeries = append(timeSeries, tSeries...)
+		cumulative = !isGaugeHistogram(record)
 
-			timeSeries = append(timeSeries, tSeries)
-			if minMaxSumCount, ok := agg.(aggregation.MinMaxSumCount); ok {
-				tSeries, err := convertFromMinMaxSumCount(record, minMaxSumCount)
-				if err != nil {
-					return err
-				}
+		// The count series is the last one converted from a histogram.
+		if e.config.DuplicateHistogramCount && cumulative {
+			name := sanitize(record.Descriptor().Name()) + "_total"
+			timeSeries = append(timeSeries, renameTimeSeries(tSeries[len(tSeries)-1], name))
+		}
+	} else if distribution, ok := agg.(aggregation.Distribution); ok && len(e.config.Quantiles) != 0 {
+		e.warnReservedLabels(record, "quantile")
+		tSeries, err := convertFromDistribution(record, distribution, e.config.Quantiles)
+		if err != nil {
+			return nil, err
+		}
 
-				timeSeries = append(timeSeries, tSeries...)
+		timeSeries = append(timeSeries, tSeries...)
+		cumulative = true
+	} else if sum, ok := agg.(aggregation.Sum); ok {
+		tSeries, err := convertFromSum(record, sum)
+		if err != nil {
+			return nil, err
+		}
+		if e.config.TemporalityMismatchPolicy != "" && record.Descriptor().MetricKind().Monotonic() {
+			if err := e.checkTemporality(record, tSeries); err != nil {
+				return nil, err
 			}
-		} else if lastValue, ok := agg.(aggregation.LastValue); ok {
-			tSeries, err := convertFromLastValue(record, lastValue)
+		}
+
+		timeSeries = append(timeSeries, tSeries)
+		cumulative = record.Descriptor().MetricKind().Monotonic()
+		if minMaxSumCount, ok := agg.(aggregation.MinMaxSumCount); ok {
+			tSeries, err := convertFromMinMaxSumCount(record, minMaxSumCount)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			timeSeries = append(timeSeries, tSeries)
-		} else {
-			// Report to the user when no conversion was found
-			fmt.Printf("No conversion found for record: %s\n", record.Descriptor().Name())
+			timeSeries = append(timeSeries, tSeries...)
+		}
+	} else if lastValue, ok := agg.(aggregation.LastValue); ok {
+		tSeries, err := convertFromLastValue(record, lastValue)
+		if err != nil {
+			return nil, err
 		}
 
-		return nil
-	})
+		timeSeries = append(timeSeries, tSeries)
+	} else {
+		// Report to the user when no conversion was found
+		e.logf("No conversion found for record: %s", record.Descriptor().Name())
+	}
 
-	// Check if error was returned in checkpointSet.ForEach()
-	if aggError != nil {
-		return nil, aggError
+	if e.config.ValueTransform != nil {
+		e.transformValues(record, timeSeries)
 	}
 
-	return timeSeries, nil
+	// A record without a start time cannot produce a meaningful created timestamp.
+	if e.config.EmitCreatedSeries && cumulative && !record.StartTime().IsZero() {
+		timeSeries = append(timeSeries, convertToCreated(record))
+	}
+
+	// Prometheus treats labels with an empty value as absent, so they are dropped by
+	// default for a consistent series identity.
+	if e.config.DropEmptyLabels == nil || *e.config.DropEmptyLabels {
+		dropEmptyLabels(timeSeries)
+	}
+	if len(e.config.RelabelConfigs) > 0 {
+		timeSeries = e.relabelTimeSeries(timeSeries)
+	}
+
+	if len(e.config.RequiredLabelDefaults) > 0 {
+		addMissingLabels(timeSeries, e.config.RequiredLabelDefaults)
+	}
+
+	// The transform is applied last so that it sees the labels that would be sent.
+	if e.config.LabelTransform != nil {
+		for _, ts := range timeSeries {
+			ts.Labels = e.config.LabelTransform(ts.Labels)
+		}
+	}
+	normalizeLabels(timeSeries)
+
+	if len(e.config.Downsample) > 0 {
+		timeSeries = e.downsample(record, timeSeries)
+	}
+
+	return e.dropEmptySeries(timeSeries), nil
+}
+
+// transformValues applies ValueTransform to the samples converted from a record, except
+// for counts and histogram buckets, which count observations instead of measuring them.
+func (e *Exporter) transformValues(record metric.Record, timeSeries []*prompb.TimeSeries) {
+	name := record.Descriptor().Name()
+	seriesName := sanitize(name)
+	for _, ts := range timeSeries {
+		isCount := false
+		for _, label := range ts.Labels {
+			if label.Name == "le" || (label.Name == "__name__" && isCountName(label.Value, seriesName)) {
+				isCount = true
+			}
+		}
+		if isCount {
+			continue
+		}
+
+		for i := range ts.Samples {
+			ts.Samples[i].Value = e.config.ValueTransform(name, ts.Samples[i].Value)
+		}
+	}
+}
+
+// isCountName returns whether a series name is the name of a count series converted from
+// a histogram or distribution with the given metric name.
+func isCountName(name, metricName string) bool {
+	return name == metricName+"_count" || name == metricName+"_gcount" || name == metricName+"_total"
+}
+
+// relabelTimeSeries applies the RelabelConfigs to every TimeSeries, and removes and
+// counts the ones they drop.
+func (e *Exporter) relabelTimeSeries(timeSeries []*prompb.TimeSeries) []*prompb.TimeSeries {
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		ts.Labels = e.relabel(ts.Labels)
+		if ts.Labels == nil {
+			e.addDroppedSeries("relabel")
+			continue
+		}
+		res = append(res, ts)
+	}
+	return res
+}
+
+// dropEmptySeries removes TimeSeries without samples, which Cortex rejects, and counts
+// them.
+func (e *Exporter) dropEmptySeries(timeSeries []*prompb.TimeSeries) []*prompb.TimeSeries {
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		if len(ts.Samples) == 0 {
+			e.addDroppedSeries("no_samples")
+			continue
+		}
+		res = append(res, ts)
+	}
+	return res
 }
 
 // createTimeSeries is a helper function to create a timeseries from a value and labels
 func createTimeSeries(record metric.Record, value apimetric.Number, extraLabels ...string) *prompb.TimeSeries {
+	return createFloatTimeSeries(record, value.CoerceToFloat64(record.Descriptor().NumberKind()), extraLabels...)
+}
+
+// createFloatTimeSeries is a helper function to create a timeseries from a value that
+// was computed by the Exporter, and therefore does not have the NumberKind of the record.
+func createFloatTimeSeries(record metric.Record, value float64, extraLabels ...string) *prompb.TimeSeries {
 	sample := prompb.Sample{
-		Value:     value.CoerceToFloat64(record.Descriptor().NumberKind()),
+		Value:     value,
 		Timestamp: record.EndTime().UnixNano() / int64(time.Millisecond),
 	}
 
@@ -196,6 +751,15 @@ func createTimeSeries(record metric.Record, value apimetric.Number, extraLabels
 	}
 }
 
+// convertToCreated returns a TimeSeries holding the start time of a cumulative Record in
+// seconds, named after the metric with a "_created" suffix. Backends use it to detect
+// counter resets, for example when the process restarts.
+func convertToCreated(record metric.Record) *prompb.TimeSeries {
+	name := sanitize(record.Descriptor().Name() + "_created")
+	created := float64(record.StartTime().UnixNano()) / float64(time.Second)
+	return createFloatTimeSeries(record, created, "__name__", name)
+}
+
 // convertFromSum returns a single TimeSeries based on a Record with a Sum aggregation
 func convertFromSum(record metric.Record, sum aggregation.Sum) (*prompb.TimeSeries, error) {
 	// Get Sum value
@@ -347,17 +911,32 @@ func convertFromDistribution(record metric.Record, distribution aggregation.Dist
 	return timeSeries, nil
 }
 
+// isGaugeHistogram returns whether a histogram record is a gauge histogram. Histograms
+// of ValueObserver instruments only describe the observations of the last collection,
+// so unlike histograms of ValueRecorder instruments, their buckets, sum, and count can
+// decrease between pushes.
+func isGaugeHistogram(record metric.Record) bool {
+	return record.Descriptor().MetricKind() == apimetric.ValueObserverKind
+}
+
 // convertFromHistogram returns
 func convertFromHistogram(record metric.Record, histogram aggregation.Histogram) ([]*prompb.TimeSeries, error) {
 	var timeSeries []*prompb.TimeSeries
 	metricName := sanitize(record.Descriptor().Name())
 
+	// Gauge histograms use different suffixes for the sum and count so that PromQL
+	// functions like rate() are not mistakenly applied to them.
+	sumName, countName := metricName+"_sum", metricName+"_count"
+	if isGaugeHistogram(record) {
+		sumName, countName = metricName+"_gsum", metricName+"_gcount"
+	}
+
 	// Create Sum TimeSeries
 	sum, err := histogram.Sum()
 	if err != nil {
 		return nil, err
 	}
-	sumTimeSeries := createTimeSeries(record, sum, "__name__", metricName+"_sum")
+	sumTimeSeries := createTimeSeries(record, sum, "__name__", sumName)
 	timeSeries = append(timeSeries, sumTimeSeries)
 
 	// Handle Histogram buckets
@@ -376,10 +955,10 @@ func convertFromHistogram(record metric.Record, histogram aggregation.Histogram)
 		counts[boundary] = totalCount
 
 		// Add upper boundary as a label. e.g. {le="5"}
-		boundaryStr := strconv.FormatFloat(boundary, 'f', -1, 64)
+		boundaryStr := formatBoundary(boundary)
 
 		// Create timeSeries and append
-		tSeries := createTimeSeries(record, apimetric.NewFloat64Number(totalCount), "__name__", metricName, "le", boundaryStr)
+		tSeries := createFloatTimeSeries(record, totalCount, "__name__", metricName, "le", boundaryStr)
 		timeSeries = append(timeSeries, tSeries)
 	}
 
@@ -388,15 +967,40 @@ func convertFromHistogram(record metric.Record, histogram aggregation.Histogram)
 
 	// Create a timeSeries for the +inf bucket and total count
 	// These are the same and are both required by Prometheus-based backends
-	upperBoundTimeSeries := createTimeSeries(record, apimetric.NewFloat64Number(totalCount), "__name__", metricName, "le", "+inf")
+	upperBoundTimeSeries := createFloatTimeSeries(record, totalCount, "__name__", metricName, "le", formatBoundary(math.Inf(1)))
 	timeSeries = append(timeSeries, upperBoundTimeSeries)
 
-	countTimeSeries := createTimeSeries(record, apimetric.NewFloat64Number(totalCount), "__name__", metricName+"_count")
+	countTimeSeries := createFloatTimeSeries(record, totalCount, "__name__", countName)
 	timeSeries = append(timeSeries, countTimeSeries)
 
 	return timeSeries, nil
 }
 
+// renameTimeSeries returns a copy of a TimeSeries with a different metric name.
+func renameTimeSeries(ts *prompb.TimeSeries, name string) *prompb.TimeSeries {
+	labels := make([]*prompb.Label, len(ts.Labels))
+	for i, label := range ts.Labels {
+		labels[i] = &prompb.Label{Name: label.Name, Value: label.Value}
+		if label.Name == "__name__" {
+			labels[i].Value = name
+		}
+	}
+	samples := make([]prompb.Sample, len(ts.Samples))
+	copy(samples, ts.Samples)
+	return &prompb.TimeSeries{Labels: labels, Samples: samples}
+}
+
+// formatBoundary formats a histogram bucket boundary for the "le" label. Boundaries are
+// always written in decimal notation (e.g. "0.005" and never "5e-03") and the upper bound
+// is written as "+Inf", matching the Prometheus client libraries. Series from different
+// exporters would not be deduplicated if the same boundary was formatted differently.
+func formatBoundary(boundary float64) string {
+	if math.IsInf(boundary, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(boundary, 'f', -1, 64)
+}
+
 // createLabelSet combines labels from a Record, resource, and extra labels to
 // create a slice of prompb.Label
 func createLabelSet(record metric.Record, extras ...string) []*prompb.Label {
@@ -423,24 +1027,24 @@ func createLabelSet(record metric.Record, extras ...string) []*prompb.Label {
 			break
 		}
 
-		// Ensure label doesn't exist. If it does, notify user that a user created label
-		// is being overwritten by a Prometheus reserved label (e.g. 'le' for histograms)
-		_, found := labelMap[extras[i]]
-		if found {
-			fmt.Printf("Label %s is overwritten. Check if Prometheus reserved labels are used.\n", extras[i])
-		}
+		// Labels created by the Exporter overwrite user created labels with the same
+		// name. The user is warned about this by convertRecord.
 		labelMap[extras[i]] = prompb.Label{
 			Name:  extras[i],
 			Value: extras[i+1],
 		}
 	}
 
-	// Create slice of labels from labelMap and return
+	// Create slice of labels from labelMap, sorted by name as the remote write
+	// specification requires, and return
 	res := make([]*prompb.Label, 0, len(labelMap))
 	for _, lb := range labelMap {
 		currentLabel := lb
 		res = append(res, &currentLabel)
 	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
 
 	return res
 }
@@ -448,11 +1052,24 @@ func createLabelSet(record metric.Record, extras ...string) []*prompb.Label {
 // addHeaders adds required headers, an Authorization header, and all headers in the
 // Config Headers map to a http request.
 func (e *Exporter) addHeaders(req *http.Request) error {
-	// Cortex expects Snappy-compressed protobuf messages. These three headers are
-	// hard-coded as they should be on every request.
+	// Cortex expects Snappy-compressed protobuf messages. These three headers are set
+	// on every request, except for the Content-Type, which some gateways require with
+	// additional parameters, and the Content-Encoding, which depends on Compression.
 	req.Header.Add("X-Prometheus-Remote-Write-Version", "0.1.0")
-	req.Header.Add("Content-Encoding", "snappy")
-	req.Header.Set("Content-Type", "application/x-protobuf")
+	if encoding := e.contentEncoding(); encoding != "" {
+		req.Header.Add("Content-Encoding", encoding)
+	}
+	contentType := "application/x-protobuf"
+	if e.config.ContentType != "" {
+		contentType = e.config.ContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	// Ask the server to confirm that it accepts the request before the body is sent, so
+	// that requests failing authentication do not upload the body first.
+	if e.config.Use100Continue {
+		req.Header.Set("Expect", "100-continue")
+	}
 
 	// Add all user-supplied headers to the request.
 	for name, field := range e.config.Headers {
@@ -467,12 +1084,16 @@ func (e *Exporter) addHeaders(req *http.Request) error {
 		if err := e.addBasicAuth(req); err != nil {
 			return err
 		}
+		if err := e.addOAuth2Auth(req); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// buildMessage creates a Snappy-compressed protobuf message from a slice of TimeSeries.
+// buildMessage creates a protobuf message from a slice of TimeSeries that is compressed
+// according to Compression.
 func (e *Exporter) buildMessage(timeseries []*prompb.TimeSeries) ([]byte, error) {
 	// Wrap the TimeSeries as a WriteRequest since Cortex requires it.
 	writeRequest := &prompb.WriteRequest{
@@ -484,50 +1105,184 @@ func (e *Exporter) buildMessage(timeseries []*prompb.TimeSeries) ([]byte, error)
 	if err != nil {
 		return nil, err
 	}
-	compressed := snappy.Encode(nil, message)
-
-	return compressed, nil
+	return e.compress(message)
 }
 
 // buildRequest creates an http POST request with a Snappy-compressed protocol buffer
 // message as the body and with all the headers attached.
 func (e *Exporter) buildRequest(message []byte) (*http.Request, error) {
-	req, err := http.NewRequest(
+	return e.buildRequestWithBody(context.Background(), bytes.NewBuffer(message))
+}
+
+// buildRequestWithBody creates an http POST request with all the headers attached that
+// reads its body from a Reader. The body is sent with chunked transfer encoding unless
+// it is a bytes.Buffer, whose length is known up front. The request is bound to ctx,
+// which also bounds fetching an OAuth2 token for it.
+func (e *Exporter) buildRequestWithBody(ctx context.Context, body io.Reader) (*http.Request, error) {
+	requestURL, err := e.requestURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPost,
-		e.config.Endpoint,
-		bytes.NewBuffer(message),
+		requestURL,
+		body,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add the required headers and the headers from Config.Headers.
-	e.addHeaders(req)
+	if err := e.addHeaders(req); err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
 
-// sendRequest sends an http request using the Exporter's http Client.
-func (e *Exporter) sendRequest(req *http.Request) error {
+// requestURL returns the URL requests are sent to. Requests to a Unix domain socket are
+// sent over HTTP to the socket, so the host in the URL is only a placeholder.
+func (e *Exporter) requestURL() (string, error) {
+	if _, path, ok := parseUnixEndpoint(e.config.Endpoint); ok {
+		return "http://unix" + path, nil
+	}
+	if e.config.UseSRVDiscovery {
+		return e.srvEndpoint()
+	}
+	return e.config.Endpoint, nil
+}
+
+// send builds a request with a compressed message and sends it. Requests that failed
+// because the endpoint could not be reached are retried according to RetryOnDialError,
+// and requests that failed transiently according to RetryConfig. The retries and the last
+// status code are recorded in result.
+func (e *Exporter) send(ctx context.Context, message []byte, result *ExportResult) error {
+	return e.sendBody(ctx, func() io.Reader { return bytes.NewBuffer(message) }, result)
+}
+
+// sendBody sends a request like send, with a body returned by the body function. The
+// function is called for every attempt since sending a request consumes its body.
+func (e *Exporter) sendBody(ctx context.Context, body func() io.Reader, result *ExportResult) error {
+	for retries := 0; ; retries++ {
+		if err := e.waitForTenant(ctx, e.requestTenant(ctx)); err != nil {
+			return err
+		}
+		release, err := e.acquireInFlight(ctx)
+		if err != nil {
+			return err
+		}
+
+		// The body is only created once the request is about to be sent, since a
+		// streamed body is encoded in the background until it is read or closed.
+		reader := body()
+		request, err := e.buildRequestWithBody(ctx, reader)
+		if err != nil {
+			release()
+			if closer, ok := reader.(io.Closer); ok {
+				closer.Close()
+			}
+			return err
+		}
+		if tenant, ok := tenantFromContext(ctx); ok {
+			request.Header.Set(tenantHeader, tenant)
+		}
+
+		var retryAfter time.Duration
+		result.StatusCode, retryAfter, err = e.sendRequest(request)
+		release()
+
+		reason := retryReason(result.StatusCode, err)
+		retry := e.retryPolicy(reason)
+		if retry == nil || retries >= retry.MaxRetries {
+			e.addRetryOutcome(retries, err)
+			return err
+		}
+
+		// The server knows best when it can accept the request again. Retries stop
+		// when the context is done before the delay has passed.
+		delay := jitter(retry.backoff(retries), rand.Float64)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		e.addRetry(reason)
+		result.Retries++
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// client returns the Exporter's http Client. A Client is built the first time if the user
+// didn't provide one. Requests may be sent concurrently, so this is done under the lock.
+func (e *Exporter) client() (*http.Client, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
 	// Set a client if the user didn't provide one.
 	if e.config.Client == nil {
 		client, err := e.buildClient()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		e.config.Client = client
 	}
+	return e.config.Client, nil
+}
+
+// closeIdleConnections closes the idle connections of the Exporter's http Client. The
+// default transport used by a Client without a Transport is shared with the rest of the
+// process and left alone.
+func (e *Exporter) closeIdleConnections() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.config.Client == nil {
+		return
+	}
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	if transport, ok := e.config.Client.Transport.(closeIdler); ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+// sendRequest sends an http request using the Exporter's http Client. It returns the
+// status code of the response, or 0 if no response was received, and how long the
+// Retry-After header of a 429 or 503 response asks to wait before retrying.
+func (e *Exporter) sendRequest(req *http.Request) (int, time.Duration, error) {
+	client, err := e.client()
+	if err != nil {
+		// The Client closes the body of every request it sends, so this is the only
+		// case in which it has to be closed here.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return 0, 0, err
+	}
 
 	// Attempt to send request.
-	res, err := e.config.Client.Do(req)
+	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	defer res.Body.Close()
 
+	// The body is read to the end so that the connection can be reused by the next
+	// request.
+	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+		return res.StatusCode, 0, err
+	}
+
 	// The response should have a status code of 200.
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%v", res.Status)
+		var retryAfter time.Duration
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		}
+		return res.StatusCode, retryAfter, fmt.Errorf("%v", res.Status)
 	}
-	return nil
+	return res.StatusCode, 0, nil
 }
@@ -15,8 +15,12 @@
 package cortex
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -32,10 +36,17 @@ import (
 
 	"go.opentelemetry.io/otel/api/global"
 	"go.opentelemetry.io/otel/api/kv"
+	apimetric "go.opentelemetry.io/otel/api/metric"
 	"go.opentelemetry.io/otel/sdk/export/metric"
 	export "go.opentelemetry.io/otel/sdk/export/metric"
 	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/export/metric/metrictest"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
+	"go.opentelemetry.io/otel/sdk/metric/controller/push"
 	"go.opentelemetry.io/otel/sdk/resource"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
 )
 
 // ValidConfig is a Config struct that should cause no errors.
@@ -54,7 +65,7 @@ var validConfig = Config{
 		"cert_file":            "certfile",
 		"key_file":             "keyfile",
 		"server_name":          "server",
-		"insecure_skip_verify": "1",
+		"insecure_skip_verify": "0",
 	},
 	ProxyURL:     "",
 	PushInterval: 10 * time.Second,
@@ -70,7 +81,7 @@ var mockTime int64 = time.Time{}.Unix()
 
 func TestExportKindFor(t *testing.T) {
 	exporter := Exporter{}
-	got := exporter.ExportKindFor(nil, aggregation.Kind(0))
+	got := exporter.ExportKindFor(nil, aggregation.SumKind)
 	want := metric.CumulativeExporter
 
 	if got != want {
@@ -143,6 +154,245 @@ func TestConvertToTimeSeries(t *testing.T) {
 	}
 }
 
+// getManyRecordsCheckpointSet returns a checkpoint set with many records of different
+// aggregation types.
+func getManyRecordsCheckpointSet(t testing.TB, records int) export.CheckpointSet {
+	checkpointSet := metrictest.NewCheckpointSet(testResource)
+	counter := apimetric.NewDescriptor("counter", apimetric.CounterKind, apimetric.Int64NumberKind)
+	recorder := apimetric.NewDescriptor("recorder", apimetric.ValueRecorderKind, apimetric.Float64NumberKind)
+
+	for i := 0; i < records; i++ {
+		labels := []kv.KeyValue{kv.Int("index", i)}
+
+		agg, ckpt := metrictest.Unslice2(sum.New(2))
+		require.NoError(t, agg.Update(context.Background(), apimetric.NewInt64Number(int64(i)), &counter))
+		require.NoError(t, agg.SynchronizedMove(ckpt, &counter))
+		checkpointSet.Add(&counter, ckpt, labels...)
+
+		agg, ckpt = metrictest.Unslice2(histogram.New(2, &recorder, []float64{100, 500, 900}))
+		for j := 0; j < 100; j++ {
+			require.NoError(t, agg.Update(context.Background(), apimetric.NewFloat64Number(float64(i*j)), &recorder))
+		}
+		require.NoError(t, agg.SynchronizedMove(ckpt, &recorder))
+		checkpointSet.Add(&recorder, ckpt, labels...)
+	}
+	return checkpointSet
+}
+
+// TestConvertConcurrently checks whether converting records concurrently produces the
+// same TimeSeries in the same order as converting them serially.
+func TestConvertConcurrently(t *testing.T) {
+	checkpointSet := getManyRecordsCheckpointSet(t, 50)
+
+	serialExporter := Exporter{config: Config{}}
+	want, err := serialExporter.ConvertToTimeSeries(checkpointSet)
+	require.Nil(t, err)
+
+	concurrentExporter := Exporter{config: Config{ConvertConcurrency: 4}}
+	got, err := concurrentExporter.ConvertToTimeSeries(checkpointSet)
+	require.Nil(t, err)
+
+	// Labels are not in a deterministic order, so series are compared by key.
+	require.Len(t, got, len(want))
+	for i := range want {
+		require.Equal(t, seriesKey(want[i].Labels), seriesKey(got[i].Labels))
+		require.Equal(t, want[i].Samples, got[i].Samples)
+	}
+}
+
+// BenchmarkConvertToTimeSeries compares converting a large CheckpointSet serially and
+// concurrently.
+func BenchmarkConvertToTimeSeries(b *testing.B) {
+	checkpointSet := getManyRecordsCheckpointSet(b, 1000)
+
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("ConvertConcurrency=%d", concurrency), func(b *testing.B) {
+			exporter := Exporter{config: Config{ConvertConcurrency: concurrency}}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := exporter.ConvertToTimeSeries(checkpointSet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestEmitCreatedSeries checks whether a "_created" series holding the start time of the
+// record accompanies counters, but not gauges.
+func TestEmitCreatedSeries(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			EmitCreatedSeries: true,
+		},
+	}
+
+	start := time.Unix(1000, 0)
+	end := time.Unix(1010, 0)
+	counter := apimetric.NewDescriptor("counter", apimetric.CounterKind, apimetric.Int64NumberKind)
+	upDownCounter := apimetric.NewDescriptor("updowncounter", apimetric.UpDownCounterKind, apimetric.Int64NumberKind)
+	checkpointSet := &recordCheckpointSet{
+		records: []export.Record{
+			newSumRecord(t, &counter, 321, start, end),
+			newSumRecord(t, &upDownCounter, 123, start, end),
+		},
+	}
+
+	timeSeries, err := exporter.ConvertToTimeSeries(checkpointSet)
+	require.Nil(t, err)
+
+	values := map[string]float64{}
+	for _, ts := range timeSeries {
+		for _, label := range ts.Labels {
+			if label.Name == "__name__" {
+				values[label.Value] = ts.Samples[0].Value
+			}
+		}
+	}
+	require.Equal(t, map[string]float64{
+		"counter":         321,
+		"counter_created": 1000,
+		"updowncounter":   123,
+	}, values)
+}
+
+// TestConvertGaugeHistogram checks whether histograms of ValueObserver instruments are
+// converted to gauge histograms, which have no _sum and _count series that could be
+// mistaken for counters.
+func TestConvertGaugeHistogram(t *testing.T) {
+	exporter := Exporter{}
+	timeSeries, err := exporter.ConvertToTimeSeries(getHistogramCheckpointOfKind(t, apimetric.ValueObserverKind))
+	require.Nil(t, err)
+
+	require.Equal(t, map[string]float64{
+		"metric_name_gsum":     500000,
+		"metric_name{le=100}":  100,
+		"metric_name{le=500}":  500,
+		"metric_name{le=900}":  900,
+		"metric_name{le=+Inf}": 1000,
+		"metric_name_gcount":   1000,
+	}, timeSeriesValues(timeSeries))
+}
+
+// TestDuplicateHistogramCount checks whether the count of a histogram is also converted to
+// a counter when DuplicateHistogramCount is set, and not for gauge histograms.
+func TestDuplicateHistogramCount(t *testing.T) {
+	exporter := Exporter{config: Config{DuplicateHistogramCount: true}}
+	timeSeries, err := exporter.ConvertToTimeSeries(getHistogramCheckpoint(t))
+	require.Nil(t, err)
+
+	values := timeSeriesValues(timeSeries)
+	require.Len(t, values, 7)
+	require.Equal(t, float64(1000), values["metric_name_count"])
+	require.Equal(t, values["metric_name_count"], values["metric_name_total"])
+
+	timeSeries, err = exporter.ConvertToTimeSeries(getHistogramCheckpointOfKind(t, apimetric.ValueObserverKind))
+	require.Nil(t, err)
+	require.NotContains(t, timeSeriesValues(timeSeries), "metric_name_total")
+}
+
+// TestValueTransform checks whether ValueTransform is applied to measured values, but not
+// to counts and histogram buckets.
+func TestValueTransform(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			ValueTransform: func(metricName string, value float64) float64 {
+				if metricName != "metric_name" {
+					return value
+				}
+				return value / 1000
+			},
+		},
+	}
+
+	timeSeries, err := exporter.ConvertToTimeSeries(getLastValueCheckpoint(t, 2048))
+	require.Nil(t, err)
+	require.Equal(t, map[string]float64{
+		"metric_name": 2.048,
+	}, timeSeriesValues(timeSeries))
+
+	timeSeries, err = exporter.ConvertToTimeSeries(getHistogramCheckpoint(t))
+	require.Nil(t, err)
+	require.Equal(t, map[string]float64{
+		"metric_name_sum":      500,
+		"metric_name{le=100}":  100,
+		"metric_name{le=500}":  500,
+		"metric_name{le=900}":  900,
+		"metric_name{le=+Inf}": 1000,
+		"metric_name_count":    1000,
+	}, timeSeriesValues(timeSeries))
+}
+
+// TestDropEmptySeries checks whether series without samples are dropped and counted.
+func TestDropEmptySeries(t *testing.T) {
+	controller := newSelfMetricsController()
+	exporter := Exporter{config: Config{MeterProvider: controller.Provider()}}
+
+	timeSeries := []*prompb.TimeSeries{
+		{
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "with_samples"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		},
+		{
+			Labels: []*prompb.Label{{Name: "__name__", Value: "without_samples"}},
+		},
+	}
+	got := exporter.dropEmptySeries(timeSeries)
+
+	require.Len(t, got, 1)
+	require.Equal(t, "with_samples", got[0].Labels[0].Value)
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_dropped_series_total{reason=no_samples}": 1,
+	}, selfMetricValues(t, controller))
+}
+
+// TestFormatBoundary checks whether histogram bucket boundaries are formatted without
+// scientific notation and whether the upper bound is formatted as "+Inf".
+func TestFormatBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary float64
+		want     string
+	}{
+		{
+			name:     "small boundary",
+			boundary: 0.005,
+			want:     "0.005",
+		},
+		{
+			name:     "boundary in scientific notation",
+			boundary: 5e-7,
+			want:     "0.0000005",
+		},
+		{
+			name:     "large boundary",
+			boundary: 2.5e10,
+			want:     "25000000000",
+		},
+		{
+			name:     "integer boundary",
+			boundary: 100,
+			want:     "100",
+		},
+		{
+			name:     "negative boundary",
+			boundary: -0.25,
+			want:     "-0.25",
+		},
+		{
+			name:     "upper bound",
+			boundary: math.Inf(1),
+			want:     "+Inf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, formatBoundary(tt.boundary))
+		})
+	}
+}
+
 // TestNewRawExporter tests whether NewRawExporter successfully creates an Exporter with
 // the same Config struct as the one passed in.
 func TestNewRawExporter(t *testing.T) {
@@ -166,6 +416,54 @@ func TestNewExportPipeline(t *testing.T) {
 	}
 }
 
+// TestPushOnStart checks whether the first push happens when the pipeline starts instead
+// of after a full push interval, unless PushOnStart is disabled.
+func TestPushOnStart(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		testName    string
+		pushOnStart *bool
+		wantPush    bool
+	}{
+		{
+			testName: "Push on start by default",
+			wantPush: true,
+		},
+		{
+			testName:    "No push on start when disabled",
+			pushOnStart: &disabled,
+			wantPush:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			pushes := make(chan struct{}, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				select {
+				case pushes <- struct{}{}:
+				default:
+				}
+			}))
+			defer server.Close()
+
+			pusher, err := NewExportPipeline(Config{
+				Endpoint:    server.URL,
+				PushOnStart: test.pushOnStart,
+			}, push.WithPeriod(time.Hour))
+			require.Nil(t, err)
+			defer pusher.Stop()
+
+			select {
+			case <-pushes:
+				require.True(t, test.wantPush)
+			case <-time.After(200 * time.Millisecond):
+				require.False(t, test.wantPush)
+			}
+		})
+	}
+}
+
 // TestInstallNewPipeline checks whether InstallNewPipeline successfully returns a push
 // Controller and whether that controller's Provider is registered globally.
 func TestInstallNewPipeline(t *testing.T) {
@@ -186,7 +484,7 @@ func TestAddHeaders(t *testing.T) {
 			"TestHeaderTwo": "TestFieldTwo",
 		},
 	}
-	exporter := Exporter{testConfig}
+	exporter := Exporter{config: testConfig}
 
 	// Create http request to add headers to.
 	req, err := http.NewRequest("POST", "test.com", nil)
@@ -202,10 +500,25 @@ func TestAddHeaders(t *testing.T) {
 	require.Equal(t, req.Header.Get("X-Prometheus-Remote-Write-Version"), "0.1.0")
 }
 
+// TestAddHeadersContentType tests whether ContentType overrides the default Content-Type
+// header.
+func TestAddHeadersContentType(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			ContentType: "application/x-protobuf;proto=prometheus.WriteRequest",
+		},
+	}
+
+	req, err := http.NewRequest("POST", "test.com", nil)
+	require.Nil(t, err)
+	require.Nil(t, exporter.addHeaders(req))
+	require.Equal(t, []string{"application/x-protobuf;proto=prometheus.WriteRequest"}, req.Header.Values("Content-Type"))
+}
+
 // TestBuildMessage tests whether BuildMessage successfully returns a Snappy-compressed
 // protobuf message.
 func TestBuildMessage(t *testing.T) {
-	exporter := Exporter{validConfig}
+	exporter := Exporter{config: validConfig}
 	timeseries := []*prompb.TimeSeries{}
 
 	// buildMessage returns the error that proto.Marshal() returns. Since the proto
@@ -220,7 +533,7 @@ func TestBuildMessage(t *testing.T) {
 func TestBuildRequest(t *testing.T) {
 	// Make fake exporter and message for testing.
 	var testMessage = []byte(`Test Message`)
-	exporter := Exporter{validConfig}
+	exporter := Exporter{config: validConfig}
 
 	// Create the http request.
 	req, err := exporter.buildRequest(testMessage)
@@ -324,7 +637,7 @@ func TestSendRequest(t *testing.T) {
 			test.config.Headers = map[string]string{
 				"isStatusNotFound": strconv.FormatBool(test.isStatusNotFound),
 			}
-			exporter := Exporter{*test.config}
+			exporter := Exporter{config: *test.config}
 
 			// Create an empty Snappy-compressed message.
 			msg, err := exporter.buildMessage([]*prompb.TimeSeries{})
@@ -335,7 +648,7 @@ func TestSendRequest(t *testing.T) {
 			require.Nil(t, err)
 
 			// Send the request to the test server and verify the error.
-			err = exporter.sendRequest(req)
+			_, _, err = exporter.sendRequest(req)
 			if err != nil {
 				errorString := err.Error()
 				require.Equal(t, errorString, test.expectedError.Error())
@@ -345,3 +658,264 @@ func TestSendRequest(t *testing.T) {
 		})
 	}
 }
+
+// TestExportWithResult checks whether ExportWithResult describes the request that was
+// sent and the response that was received.
+func TestExportWithResult(t *testing.T) {
+	var bodySize, uncompressedSize int
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		bodySize = len(body)
+		uncompressed, err := snappy.Decode(nil, body)
+		require.Nil(t, err)
+		uncompressedSize = len(uncompressed)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{config: Config{Endpoint: server.URL}}
+	result, err := exporter.ExportWithResult(context.Background(), getHistogramCheckpoint(t))
+	require.Nil(t, err)
+
+	require.Equal(t, ExportResult{
+		SeriesSent:        6,
+		BytesSent:         bodySize,
+		UncompressedBytes: uncompressedSize,
+		Retries:           0,
+		StatusCode:        http.StatusOK,
+	}, result)
+	require.NotZero(t, result.BytesSent)
+}
+
+// TestCompressionRatio checks whether the compression ratio of a push is recorded as a
+// self-metric.
+func TestCompressionRatio(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			MeterProvider: controller.Provider(),
+		},
+	}
+	result, err := exporter.ExportWithResult(context.Background(), getHistogramCheckpoint(t))
+	require.Nil(t, err)
+
+	values := selfMetricValues(t, controller)
+	require.Contains(t, values, "cortex_exporter_compression_ratio{}")
+	require.Equal(t, float64(result.UncompressedBytes)/float64(result.BytesSent), values["cortex_exporter_compression_ratio{}"])
+}
+
+// TestReuseConnections checks whether consecutive pushes reuse the same keep-alive
+// connection unless ReuseConnections is disabled.
+func TestReuseConnections(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		testName         string
+		reuseConnections *bool
+		wantConnections  int
+	}{
+		{
+			testName:        "Connections are reused by default",
+			wantConnections: 1,
+		},
+		{
+			testName:         "Connections are not reused when disabled",
+			reuseConnections: &disabled,
+			wantConnections:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				_, _ = rw.Write([]byte("accepted"))
+			}))
+			connections := 0
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					connections++
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			exporter, err := NewRawExporter(Config{
+				Endpoint:         server.URL,
+				ReuseConnections: test.reuseConnections,
+			})
+			require.Nil(t, err)
+
+			for i := 0; i < 2; i++ {
+				require.Nil(t, exporter.Export(context.Background(), getValidCheckpointSet(t)))
+			}
+			server.Close()
+			require.Equal(t, test.wantConnections, connections)
+		})
+	}
+}
+
+// failingCheckpointSet is a CheckpointSet that fails with an error after iterating over
+// its records, like a collection that could only be read partially.
+type failingCheckpointSet struct {
+	recordCheckpointSet
+	err error
+}
+
+// ForEach calls f for each record and then returns the error of the CheckpointSet.
+func (c *failingCheckpointSet) ForEach(kind export.ExportKindSelector, f func(export.Record) error) error {
+	if err := c.recordCheckpointSet.ForEach(kind, f); err != nil {
+		return err
+	}
+	return c.err
+}
+
+// TestPartialCollectionPolicy checks whether the records collected before a collection
+// error are sent or the push is aborted depending on PartialCollectionPolicy.
+func TestPartialCollectionPolicy(t *testing.T) {
+	tests := []struct {
+		testName           string
+		policy             string
+		convertConcurrency int
+		wantSeries         []int
+	}{
+		{
+			testName:   "Abort",
+			policy:     PartialCollectionPolicyAbort,
+			wantSeries: nil,
+		},
+		{
+			testName:   "Send",
+			policy:     PartialCollectionPolicySend,
+			wantSeries: []int{1},
+		},
+		{
+			testName:           "Send with concurrent conversion",
+			policy:             PartialCollectionPolicySend,
+			convertConcurrency: 2,
+			wantSeries:         []int{1},
+		},
+	}
+
+	collectionError := fmt.Errorf("collection failed")
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var series []int
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				require.Nil(t, err)
+				writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+				require.Nil(t, err)
+				series = append(series, len(writeRequest.Timeseries))
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:                server.URL,
+					ConvertConcurrency:      test.convertConcurrency,
+					PartialCollectionPolicy: test.policy,
+				},
+			}
+			checkpointSet := &failingCheckpointSet{
+				recordCheckpointSet: recordCheckpointSet{
+					records: []export.Record{newSumRecord(t, &desc, 1, time.Time{}, time.Now())},
+				},
+				err: collectionError,
+			}
+
+			err := exporter.Export(context.Background(), checkpointSet)
+			require.Equal(t, collectionError, err)
+			require.Equal(t, test.wantSeries, series)
+		})
+	}
+}
+
+// TestWarmUpConnection checks whether a connection is established when the Exporter is
+// created and reused by the first push.
+func TestWarmUpConnection(t *testing.T) {
+	var methods []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+	}))
+	connections := 0
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections++
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	exporter, err := NewRawExporter(Config{
+		Endpoint:         server.URL,
+		WarmUpConnection: true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{http.MethodHead}, methods)
+
+	require.Nil(t, exporter.Export(context.Background(), getValidCheckpointSet(t)))
+	server.Close()
+	require.Equal(t, []string{http.MethodHead, http.MethodPost}, methods)
+	require.Equal(t, 1, connections)
+}
+
+// TestWarmUpTimeout checks whether creating an Exporter waits for an unresponsive
+// endpoint only for the warm-up timeout, which is a tenth of RemoteTimeout.
+func TestWarmUpTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	start := time.Now()
+	_, err := NewRawExporter(Config{
+		Endpoint:         server.URL,
+		RemoteTimeout:    10 * time.Second,
+		WarmUpConnection: true,
+	})
+	require.Nil(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	require.Equal(t, maxWarmUpTimeout, (&Exporter{}).warmUpTimeout())
+	require.Equal(t, time.Second, (&Exporter{config: Config{RemoteTimeout: 10 * time.Second}}).warmUpTimeout())
+	require.Equal(t, maxWarmUpTimeout, (&Exporter{config: Config{RemoteTimeout: time.Minute}}).warmUpTimeout())
+}
+
+// TestExportContextDeadline checks whether a push ends at the deadline of the context
+// passed to Export when it is shorter than RemoteTimeout.
+func TestExportContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := NewRawExporter(Config{
+		Endpoint:      server.URL,
+		RemoteTimeout: 30 * time.Second,
+		PushInterval:  10 * time.Second,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = exporter.Export(ctx, getValidCheckpointSet(t))
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	require.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortextest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var (
+	// ErrUnsupportedEncoding occurs when DecodeWriteRequest is called with a
+	// Content-Encoding it cannot decompress.
+	ErrUnsupportedEncoding = fmt.Errorf("Unsupported content encoding")
+)
+
+// DecodeWriteRequest decompresses a request body sent with the given Content-Encoding
+// and unmarshals the WriteRequest it holds, so that tests can assert on what the
+// Exporter sent. "snappy", "gzip", and uncompressed bodies, whose encoding is empty or
+// "identity", are supported.
+func DecodeWriteRequest(body []byte, encoding string) (*prompb.WriteRequest, error) {
+	uncompressed, err := decompress(body, encoding)
+	if err != nil {
+		return nil, err
+	}
+	var writeRequest prompb.WriteRequest
+	if err := proto.Unmarshal(uncompressed, &writeRequest); err != nil {
+		return nil, err
+	}
+	return &writeRequest, nil
+}
+
+// decompress decompresses a body according to its Content-Encoding.
+func decompress(body []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "snappy":
+		return snappy.Decode(nil, body)
+	case "gzip":
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	case "", "identity":
+		return body, nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
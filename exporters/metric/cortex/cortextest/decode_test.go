@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortextest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestDecodeWriteRequest checks whether a WriteRequest compressed with each supported
+// Content-Encoding round-trips through DecodeWriteRequest and whether unsupported or
+// corrupt bodies are rejected.
+func TestDecodeWriteRequest(t *testing.T) {
+	want := prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{
+			{
+				Labels:  []*prompb.Label{{Name: "__name__", Value: "metric_name"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+			},
+		},
+	}
+	message, err := proto.Marshal(&want)
+	require.Nil(t, err)
+
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err = writer.Write(message)
+	require.Nil(t, err)
+	require.Nil(t, writer.Close())
+
+	tests := []struct {
+		testName string
+		encoding string
+		body     []byte
+	}{
+		{
+			testName: "snappy",
+			encoding: "snappy",
+			body:     snappy.Encode(nil, message),
+		},
+		{
+			testName: "gzip",
+			encoding: "gzip",
+			body:     gzipped.Bytes(),
+		},
+		{
+			testName: "identity",
+			encoding: "identity",
+			body:     message,
+		},
+		{
+			testName: "no encoding",
+			encoding: "",
+			body:     message,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			got, err := cortextest.DecodeWriteRequest(test.body, test.encoding)
+			require.Nil(t, err)
+			require.Equal(t, want.Timeseries, got.Timeseries)
+		})
+	}
+
+	_, err = cortextest.DecodeWriteRequest(message, "br")
+	require.Equal(t, cortextest.ErrUnsupportedEncoding, err)
+
+	_, err = cortextest.DecodeWriteRequest(message, "snappy")
+	require.Error(t, err)
+
+	_, err = cortextest.DecodeWriteRequest(message, "gzip")
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cortextest provides a fake Cortex remote write endpoint for testing and
+// benchmarking code that uses the Cortex exporter.
+package cortextest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Options configures the behavior of a Server.
+type Options struct {
+	// Latency is waited before every response.
+	Latency time.Duration
+
+	// StatusCodes are returned to the first requests in order. Requests after that are
+	// answered with 200 OK.
+	StatusCodes []int
+
+	// Validate makes the Server answer requests without the remote write headers with
+	// 400 Bad Request.
+	Validate bool
+}
+
+// Server is a fake Cortex remote write endpoint that decodes and records the
+// WriteRequests it accepts.
+type Server struct {
+	*httptest.Server
+	options Options
+
+	lock     sync.Mutex
+	count    int
+	requests []prompb.WriteRequest
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when finished
+// to shut it down.
+func NewServer(options Options) *Server {
+	s := &Server{options: options}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Requests returns the WriteRequests accepted by the Server in the order they were
+// received.
+func (s *Server) Requests() []prompb.WriteRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	requests := make([]prompb.WriteRequest, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// handle decodes a WriteRequest according to the Content-Encoding of the request and
+// records it if the request is accepted.
+func (s *Server) handle(rw http.ResponseWriter, req *http.Request) {
+	time.Sleep(s.options.Latency)
+
+	// Respond with the next simulated status code if there is one left.
+	s.lock.Lock()
+	statusCode := http.StatusOK
+	if s.count < len(s.options.StatusCodes) {
+		statusCode = s.options.StatusCodes[s.count]
+	}
+	s.count++
+	s.lock.Unlock()
+	if statusCode != http.StatusOK {
+		rw.WriteHeader(statusCode)
+		return
+	}
+
+	if s.options.Validate && !hasRemoteWriteHeaders(req) {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	compressed, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeRequest, err := DecodeWriteRequest(compressed, req.Header.Get("Content-Encoding"))
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	s.requests = append(s.requests, *writeRequest)
+	s.lock.Unlock()
+}
+
+// hasRemoteWriteHeaders returns whether a request has the headers required by the
+// remote write protocol.
+func hasRemoteWriteHeaders(req *http.Request) bool {
+	return req.Header.Get("X-Prometheus-Remote-Write-Version") == "0.1.0" &&
+		req.Header.Get("Content-Encoding") == "snappy" &&
+		req.Header.Get("Content-Type") == "application/x-protobuf"
+}
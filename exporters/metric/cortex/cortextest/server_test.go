@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortextest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/metrictest"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/aggregatortest"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex"
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestServer checks whether the Server fails the first push with a simulated 503 and
+// then records the WriteRequest of the next push.
+func TestServer(t *testing.T) {
+	server := cortextest.NewServer(cortextest.Options{
+		StatusCodes: []int{http.StatusServiceUnavailable},
+		Validate:    true,
+	})
+	defer server.Close()
+
+	exporter, err := cortex.NewRawExporter(cortex.Config{Endpoint: server.URL})
+	require.Nil(t, err)
+
+	// Create a checkpoint set with a single counter.
+	checkpointSet := metrictest.NewCheckpointSet(resource.New(kv.String("R", "V")))
+	desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+	agg, ckpt := metrictest.Unslice2(sum.New(2))
+	aggregatortest.CheckedUpdate(t, agg, metric.NewInt64Number(321), &desc)
+	require.NoError(t, agg.SynchronizedMove(ckpt, &desc))
+	checkpointSet.Add(&desc, ckpt)
+
+	err = exporter.Export(context.Background(), checkpointSet)
+	require.EqualError(t, err, "503 Service Unavailable")
+	require.Empty(t, server.Requests())
+
+	require.Nil(t, exporter.Export(context.Background(), checkpointSet))
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].Timeseries, 1)
+	require.Equal(t, 321.0, requests[0].Timeseries[0].Samples[0].Value)
+}
+
+// TestServerCompression checks whether the Server decodes requests according to their
+// Content-Encoding for every Compression of the Exporter.
+func TestServerCompression(t *testing.T) {
+	for _, compression := range []string{cortex.CompressionSnappy, cortex.CompressionGzip, cortex.CompressionNone} {
+		t.Run(compression, func(t *testing.T) {
+			server := cortextest.NewServer(cortextest.Options{})
+			defer server.Close()
+
+			exporter, err := cortex.NewRawExporter(cortex.Config{
+				Endpoint:    server.URL,
+				Compression: compression,
+			})
+			require.Nil(t, err)
+
+			// Create a checkpoint set with a single counter.
+			checkpointSet := metrictest.NewCheckpointSet(resource.New(kv.String("R", "V")))
+			desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+			agg, ckpt := metrictest.Unslice2(sum.New(2))
+			aggregatortest.CheckedUpdate(t, agg, metric.NewInt64Number(321), &desc)
+			require.NoError(t, agg.SynchronizedMove(ckpt, &desc))
+			checkpointSet.Add(&desc, ckpt)
+
+			require.Nil(t, exporter.Export(context.Background(), checkpointSet))
+			requests := server.Requests()
+			require.Len(t, requests, 1)
+			require.Len(t, requests[0].Timeseries, 1)
+			require.Equal(t, 321.0, requests[0].Timeseries[0].Samples[0].Value)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// seriesKey returns a string that identifies a TimeSeries by its label set, regardless
+// of the order of the labels.
+func seriesKey(labels []*prompb.Label) string {
+	sorted := make([]*prompb.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var key strings.Builder
+	for _, label := range sorted {
+		key.WriteString(label.Name)
+		key.WriteByte(0xff)
+		key.WriteString(label.Value)
+		key.WriteByte(0xff)
+	}
+	return key.String()
+}
+
+// dedupInterval returns how long unchanged samples are suppressed for, or 0 if they are
+// always sent. OnlySendUpdated suppresses them for KeepaliveInterval, which is shorter
+// than the time after which Prometheus-based backends consider a series stale.
+func (e *Exporter) dedupInterval() time.Duration {
+	if e.config.DedupUnchangedInterval > 0 {
+		return e.config.DedupUnchangedInterval
+	}
+	if e.config.OnlySendUpdated {
+		return e.config.KeepaliveInterval
+	}
+	return 0
+}
+
+// dedupUnchanged removes samples whose value is identical to the last sample sent for
+// the same series, unless the dedup interval has passed since that sample. Series left
+// without samples are removed entirely. The samples that are kept are returned by series
+// key as well, and only count as sent once they are passed to commitLastSent after the
+// push succeeded, so that a failed push does not suppress the samples of later pushes.
+func (e *Exporter) dedupUnchanged(timeSeries []*prompb.TimeSeries) ([]*prompb.TimeSeries, map[string]prompb.Sample) {
+	interval := int64(e.dedupInterval() / time.Millisecond)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	kept := make(map[string]prompb.Sample)
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		key := seriesKey(ts.Labels)
+
+		samples := ts.Samples[:0]
+		for _, sample := range ts.Samples {
+			// Timestamps are compared instead of the wall clock so that the interval
+			// is measured in the same time frame as the samples themselves.
+			last, found := kept[key]
+			if !found {
+				last, found = e.lastSent[key]
+			}
+			if found && last.Value == sample.Value && sample.Timestamp-last.Timestamp < interval {
+				continue
+			}
+			kept[key] = sample
+			samples = append(samples, sample)
+		}
+
+		if len(samples) == 0 {
+			continue
+		}
+		ts.Samples = samples
+		res = append(res, ts)
+	}
+	return res, kept
+}
+
+// commitLastSent records the samples dedupUnchanged kept as the last ones sent for their
+// series. Series whose last sample is older than the dedup interval, relative to the
+// newest sample committed, are evicted, since their next sample is sent regardless and
+// they may no longer exist.
+func (e *Exporter) commitLastSent(kept map[string]prompb.Sample) {
+	if len(kept) == 0 {
+		return
+	}
+	interval := int64(e.dedupInterval() / time.Millisecond)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.lastSent == nil {
+		e.lastSent = make(map[string]prompb.Sample)
+	}
+
+	newest := int64(math.MinInt64)
+	for key, sample := range kept {
+		e.lastSent[key] = sample
+		if sample.Timestamp > newest {
+			newest = sample.Timestamp
+		}
+	}
+	for key, sample := range e.lastSent {
+		if newest-sample.Timestamp >= interval {
+			delete(e.lastSent, key)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestDedupUnchanged checks whether unchanged samples are suppressed until
+// DedupUnchangedInterval has passed since the last sample that was sent.
+func TestDedupUnchanged(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			DedupUnchangedInterval: time.Minute,
+		},
+	}
+
+	tests := []struct {
+		testName  string
+		value     float64
+		timestamp time.Duration
+		wantSent  bool
+	}{
+		{
+			testName:  "First sample is sent",
+			value:     1,
+			timestamp: 0,
+			wantSent:  true,
+		},
+		{
+			testName:  "Unchanged sample is suppressed",
+			value:     1,
+			timestamp: 10 * time.Second,
+			wantSent:  false,
+		},
+		{
+			testName:  "Changed sample is sent",
+			value:     2,
+			timestamp: 20 * time.Second,
+			wantSent:  true,
+		},
+		{
+			testName:  "Unchanged sample within the interval is suppressed",
+			value:     2,
+			timestamp: 70 * time.Second,
+			wantSent:  false,
+		},
+		{
+			testName:  "Unchanged sample after the interval is sent",
+			value:     2,
+			timestamp: 80 * time.Second,
+			wantSent:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			timeSeries := []*prompb.TimeSeries{
+				{
+					Labels: []*prompb.Label{
+						{Name: "__name__", Value: "metric_name"},
+						{Name: "R", Value: "V"},
+					},
+					Samples: []prompb.Sample{{
+						Value:     test.value,
+						Timestamp: int64(test.timestamp / time.Millisecond),
+					}},
+				},
+			}
+
+			got, kept := exporter.dedupUnchanged(timeSeries)
+			exporter.commitLastSent(kept)
+			if test.wantSent {
+				require.Len(t, got, 1)
+				require.Equal(t, test.value, got[0].Samples[0].Value)
+			} else {
+				require.Empty(t, got)
+			}
+		})
+	}
+}
+
+// TestSeriesKey checks whether the series key does not depend on the order of labels.
+func TestSeriesKey(t *testing.T) {
+	labels := []*prompb.Label{
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "R", Value: "V"},
+	}
+	reversed := []*prompb.Label{labels[1], labels[0]}
+
+	require.Equal(t, seriesKey(labels), seriesKey(reversed))
+	require.NotEqual(t, seriesKey(labels), seriesKey(labels[:1]))
+}
+
+// TestOnlySendUpdated checks whether unchanged series are skipped until
+// KeepaliveInterval has passed since they were last sent.
+func TestOnlySendUpdated(t *testing.T) {
+	config := Config{OnlySendUpdated: true}
+	require.Nil(t, config.Validate())
+	require.Equal(t, 4*time.Minute, config.KeepaliveInterval)
+	exporter := Exporter{config: config}
+
+	var sent []time.Duration
+	for timestamp := time.Duration(0); timestamp <= 5*time.Minute; timestamp += time.Minute {
+		timeSeries := []*prompb.TimeSeries{
+			{
+				Labels: []*prompb.Label{
+					{Name: "__name__", Value: "metric_name"},
+				},
+				Samples: []prompb.Sample{{
+					Value:     1,
+					Timestamp: int64(timestamp / time.Millisecond),
+				}},
+			},
+		}
+		got, kept := exporter.dedupUnchanged(timeSeries)
+		exporter.commitLastSent(kept)
+		if len(got) == 1 {
+			sent = append(sent, timestamp)
+		}
+	}
+
+	require.Equal(t, []time.Duration{0, 4 * time.Minute}, sent)
+}
+
+// TestDedupFailedPush checks whether samples of a failed push do not suppress the same
+// samples in later pushes.
+func TestDedupFailedPush(t *testing.T) {
+	requests := 0
+	samples := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		requests++
+		if requests == 1 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, ts := range writeRequest.Timeseries {
+			samples += len(ts.Samples)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:               server.URL,
+			DedupUnchangedInterval: time.Minute,
+		},
+	}
+
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Equal(t, 1, samples)
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Equal(t, 1, samples)
+}
+
+// TestCommitLastSentEviction checks whether series whose last sample is older than the
+// dedup interval are evicted.
+func TestCommitLastSentEviction(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			DedupUnchangedInterval: time.Minute,
+		},
+	}
+
+	exporter.commitLastSent(map[string]prompb.Sample{
+		"old": {Value: 1, Timestamp: 0},
+		"new": {Value: 1, Timestamp: 0},
+	})
+	exporter.commitLastSent(map[string]prompb.Sample{
+		"new": {Value: 1, Timestamp: int64(time.Minute / time.Millisecond)},
+	})
+
+	require.Len(t, exporter.lastSent, 1)
+	require.Contains(t, exporter.lastSent, "new")
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// downsampleState holds how many collections of a record were seen since it was last
+// sent, and when it was last seen.
+type downsampleState struct {
+	count int
+	seen  time.Time
+}
+
+// downsampleRate returns every how many collections a record is sent according to
+// Downsample, which is keyed by the instrument name or its sanitized form. It returns 1
+// for records that are always sent.
+func (e *Exporter) downsampleRate(record metric.Record) int {
+	name := record.Descriptor().Name()
+	if n, ok := e.config.Downsample[name]; ok {
+		return n
+	}
+	if n, ok := e.config.Downsample[sanitize(name)]; ok {
+		return n
+	}
+	return 1
+}
+
+// downsample returns the TimeSeries converted from a record only for every Nth collection
+// of the record, where N is its rate in Downsample, starting with the first. All series of
+// a record, such as the buckets of a histogram, are kept or dropped together.
+func (e *Exporter) downsample(record metric.Record, timeSeries []*prompb.TimeSeries) []*prompb.TimeSeries {
+	n := e.downsampleRate(record)
+	if n <= 1 || len(timeSeries) == 0 {
+		return timeSeries
+	}
+
+	// The series of a record share their labels apart from the name and labels like le,
+	// so the first one identifies the record.
+	key := record.Descriptor().Name() + "\xff" + seriesKey(timeSeries[0].Labels)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.downsampleCounts == nil {
+		e.downsampleCounts = make(map[string]downsampleState)
+	}
+	count := e.downsampleCounts[key].count
+	e.downsampleCounts[key] = downsampleState{count: (count + 1) % n, seen: record.EndTime()}
+	if count != 0 {
+		return nil
+	}
+	return timeSeries
+}
+
+// evictDownsampleCounts removes the counts of records that were not seen for
+// seriesStateTTL, relative to the newest record seen, since their label sets may no
+// longer exist. A record that appears again is sent with its first collection.
+func (e *Exporter) evictDownsampleCounts() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var newest time.Time
+	for _, state := range e.downsampleCounts {
+		if state.seen.After(newest) {
+			newest = state.seen
+		}
+	}
+	for key, state := range e.downsampleCounts {
+		if newest.Sub(state.seen) >= e.seriesStateTTL() {
+			delete(e.downsampleCounts, key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestDownsample checks whether a configured metric is only sent for every Nth collection
+// while other metrics are sent for every collection.
+func TestDownsample(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			Downsample: map[string]int{"high.frequency": 3},
+		},
+	}
+
+	downsampled := metric.NewDescriptor("high.frequency", metric.CounterKind, metric.Int64NumberKind)
+	regular := metric.NewDescriptor("regular", metric.CounterKind, metric.Int64NumberKind)
+
+	var sent []int64
+	var regularSent int
+	for i := int64(0); i < 7; i++ {
+		records := []export.Record{
+			newSumRecord(t, &downsampled, i, time.Time{}, time.Time{}),
+			newSumRecord(t, &regular, i, time.Time{}, time.Time{}),
+		}
+		timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+		require.Nil(t, err)
+
+		for _, ts := range timeSeries {
+			switch metricName(ts) {
+			case "high_frequency":
+				sent = append(sent, int64(ts.Samples[0].Value))
+			case "regular":
+				regularSent++
+			}
+		}
+	}
+
+	require.Equal(t, []int64{0, 3, 6}, sent)
+	require.Equal(t, 7, regularSent)
+}
+
+// TestEvictDownsampleCounts checks whether the counts of records that were not seen for
+// seriesStateTTL are removed.
+func TestEvictDownsampleCounts(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			Downsample:   map[string]int{"gone": 3, "kept": 3},
+			PushInterval: 10 * time.Second,
+		},
+	}
+	gone := metric.NewDescriptor("gone", metric.CounterKind, metric.Int64NumberKind)
+	kept := metric.NewDescriptor("kept", metric.CounterKind, metric.Int64NumberKind)
+
+	_, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &gone, 1, time.Unix(0, 0), time.Unix(10, 0)),
+		newSumRecord(t, &kept, 1, time.Unix(0, 0), time.Unix(10, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictDownsampleCounts()
+	require.Len(t, exporter.downsampleCounts, 2)
+
+	// The count of a record is kept while it was seen within 10 push intervals.
+	_, err = exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &kept, 2, time.Unix(0, 0), time.Unix(100, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictDownsampleCounts()
+	require.Len(t, exporter.downsampleCounts, 2)
+
+	_, err = exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &kept, 3, time.Unix(0, 0), time.Unix(110, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictDownsampleCounts()
+	require.Len(t, exporter.downsampleCounts, 1)
+}
@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Stop flushes the data the Exporter buffers, which are the pending segments of the WAL,
+// after waiting for a push in flight to finish. It is meant to be called once the push
+// Controller is stopped. The flush is bounded by both the deadline of ctx and
+// DrainTimeout, whichever ends first. Segments that are not sent by then are dropped and
+// their samples are counted as dropped, so that Stop returns in a predictable time.
+// Idle connections of the http Client are closed afterwards.
+func (e *Exporter) Stop(ctx context.Context) error {
+	defer e.closeIdleConnections()
+	if !e.walEnabled() {
+		return nil
+	}
+	if e.config.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.DrainTimeout)
+		defer cancel()
+	}
+
+	e.walLock.Lock()
+	defer e.walLock.Unlock()
+
+	// Segments that failed for another reason are kept and sent after a restart.
+	err := e.replayWAL(ctx)
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	return e.dropWAL()
+}
+
+// dropWAL deletes all pending segments and counts their samples as dropped. It is called
+// with walLock held.
+func (e *Exporter) dropWAL() error {
+	paths, err := filepath.Glob(filepath.Join(e.config.WALConfig.Directory, "*"+walPendingSuffix))
+	if err != nil {
+		return err
+	}
+
+	samples := 0
+	for _, path := range paths {
+		samples += segmentSamples(path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if len(paths) > 0 {
+		e.logf("Drain did not finish in time, dropped %d samples of %d WAL segments", samples, len(paths))
+	}
+	e.addDroppedSamples(samples, "drain_timeout")
+	return nil
+}
+
+// segmentSamples returns the number of samples in a WAL segment, or 0 if it cannot be
+// read.
+func segmentSamples(path string) int {
+	compressed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	message, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return 0
+	}
+	var writeRequest prompb.WriteRequest
+	if err := proto.Unmarshal(message, &writeRequest); err != nil {
+		return 0
+	}
+
+	samples := 0
+	for _, ts := range writeRequest.Timeseries {
+		samples += len(ts.Samples)
+	}
+	return samples
+}
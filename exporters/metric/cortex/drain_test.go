@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStopDrainTimeout checks whether Stop sends pending segments until DrainTimeout has
+// passed and then drops and counts the remaining ones.
+func TestStopDrainTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The first request succeeds and the ones after it hang until the test ends.
+	done := make(chan struct{})
+	var requests int32
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		ioutil.ReadAll(req.Body)
+		if atomic.AddInt32(&requests, 1) > 1 {
+			<-done
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	defer close(done)
+
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			RemoteTimeout: 30 * time.Second,
+			DrainTimeout:  100 * time.Millisecond,
+			MeterProvider: controller.Provider(),
+			WALConfig:     &WALConfig{Enabled: true, Directory: dir, TruncateFrequency: time.Hour},
+		},
+	}
+	for _, n := range []int{1, 2, 3} {
+		_, err := exporter.appendWAL(makeTimeSeries(n))
+		require.Nil(t, err)
+	}
+
+	start := time.Now()
+	require.Nil(t, exporter.Stop(context.Background()))
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+	require.Len(t, walFiles(t, dir, walAckedSuffix), 1)
+	require.Equal(t, float64(5), selfMetricValues(t, controller)["cortex_exporter_dropped_samples_total{reason=drain_timeout}"])
+}
+
+// TestStopFlush checks whether Stop sends all pending segments when Cortex accepts them
+// and keeps them when Cortex fails them before the drain times out.
+func TestStopFlush(t *testing.T) {
+	tests := []struct {
+		testName    string
+		statusCode  int
+		wantErr     bool
+		wantPending int
+	}{
+		{
+			testName:    "Accepted",
+			statusCode:  http.StatusOK,
+			wantErr:     false,
+			wantPending: 0,
+		},
+		{
+			testName:    "Failed",
+			statusCode:  http.StatusServiceUnavailable,
+			wantErr:     true,
+			wantPending: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "wal")
+			require.Nil(t, err)
+			defer os.RemoveAll(dir)
+
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(test.statusCode)
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:     server.URL,
+					DrainTimeout: time.Minute,
+					WALConfig:    &WALConfig{Enabled: true, Directory: dir, TruncateFrequency: time.Hour},
+				},
+			}
+			for i := 0; i < 2; i++ {
+				_, err := exporter.appendWAL(makeTimeSeries(1))
+				require.Nil(t, err)
+			}
+
+			err = exporter.Stop(context.Background())
+			require.Equal(t, test.wantErr, err != nil)
+			require.Len(t, walFiles(t, dir, walPendingSuffix), test.wantPending)
+		})
+	}
+}
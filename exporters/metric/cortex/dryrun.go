@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// dryRunLabelSets is the number of label sets a dry run logs.
+const dryRunLabelSets = 5
+
+// logDryRun builds the WriteRequest for TimeSeries and logs a summary of it instead of
+// sending it: the number of series and samples, the size of the request, and the label
+// sets of the first series.
+func (e *Exporter) logDryRun(timeSeries []*prompb.TimeSeries) error {
+	message, err := proto.Marshal(&prompb.WriteRequest{Timeseries: timeSeries})
+	if err != nil {
+		return err
+	}
+
+	samples := 0
+	for _, ts := range timeSeries {
+		samples += len(ts.Samples)
+	}
+	e.logf("Dry run: %d series with %d samples in a %d byte WriteRequest are not sent", len(timeSeries), samples, len(message))
+
+	for i, ts := range timeSeries {
+		if i == dryRunLabelSets {
+			e.logf("  ... and %d more series", len(timeSeries)-dryRunLabelSets)
+			break
+		}
+		e.logf("  %s", formatLabels(ts.Labels))
+	}
+	return nil
+}
+
+// formatLabels formats a label set sorted by name like PromQL does, e.g.
+// {__name__="metric_name", R="V"}.
+func formatLabels(labels []*prompb.Label) string {
+	sorted := make([]*prompb.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	pairs := make([]string, len(sorted))
+	for i, label := range sorted {
+		pairs[i] = label.Name + "=" + strconv.Quote(label.Value)
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
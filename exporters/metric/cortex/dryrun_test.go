@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDryRun checks whether a dry run logs a summary of the WriteRequest and sends no
+// request.
+func TestDryRun(t *testing.T) {
+	requests := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	exporter := Exporter{
+		config: Config{
+			Endpoint: server.URL,
+			DryRun:   true,
+			Logger:   log.New(&logs, "", 0),
+		},
+	}
+
+	result, err := exporter.ExportWithResult(context.Background(), getHistogramCheckpoint(t))
+	require.Nil(t, err)
+	require.Equal(t, ExportResult{}, result)
+	require.Equal(t, 0, requests)
+
+	lines := strings.Split(strings.TrimSuffix(logs.String(), "\n"), "\n")
+	require.Len(t, lines, 7)
+	require.True(t, strings.HasPrefix(lines[0], "Dry run: 6 series with 6 samples in a "))
+	require.Equal(t, []string{
+		`  {R="V", __name__="metric_name_sum"}`,
+		`  {R="V", __name__="metric_name", le="100"}`,
+		`  {R="V", __name__="metric_name", le="500"}`,
+		`  {R="V", __name__="metric_name", le="900"}`,
+		`  {R="V", __name__="metric_name", le="+Inf"}`,
+		"  ... and 1 more series",
+	}, lines[1:])
+}
+
+// TestFormatLabels checks whether label sets are formatted sorted by name with quoted
+// values.
+func TestFormatLabels(t *testing.T) {
+	labels := []*prompb.Label{
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "A", Value: `quoted "value"`},
+	}
+	require.Equal(t, `{A="quoted \"value\"", __name__="metric_name"}`, formatLabels(labels))
+	require.Equal(t, "{}", formatLabels(nil))
+}
@@ -0,0 +1,84 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// DuplicateScopePolicyMerge merges series with the same label set, which occur when
+	// instruments with the same name are registered by more than one instrumentation
+	// library, into one series. Of samples with the same timestamp, the first is kept.
+	DuplicateScopePolicyMerge = "merge"
+
+	// DuplicateScopePolicyError fails a push that contains more than one series with the
+	// same label set.
+	DuplicateScopePolicyError = "error"
+)
+
+var (
+	// ErrDuplicateSeries occurs when a push contains more than one series with the same
+	// label set and DuplicateScopePolicy is "error".
+	ErrDuplicateSeries = fmt.Errorf("Push contains more than one series with the same labels")
+)
+
+// checkDuplicateSeries handles TimeSeries with the same label set according to
+// DuplicateScopePolicy. It returns the TimeSeries left after merging duplicates.
+func (e *Exporter) checkDuplicateSeries(timeSeries []*prompb.TimeSeries) ([]*prompb.TimeSeries, error) {
+	seen := make(map[string]*prompb.TimeSeries, len(timeSeries))
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		key := seriesKey(ts.Labels)
+		first, found := seen[key]
+		if !found {
+			seen[key] = ts
+			res = append(res, ts)
+			continue
+		}
+		if e.config.DuplicateScopePolicy == DuplicateScopePolicyError {
+			return nil, ErrDuplicateSeries
+		}
+		first.Samples = e.mergeSamples(first.Samples, ts.Samples)
+	}
+	return res, nil
+}
+
+// mergeSamples returns the samples of two series in timestamp order. Samples of the
+// second series with a timestamp that is already present are dropped and counted.
+func (e *Exporter) mergeSamples(first, second []prompb.Sample) []prompb.Sample {
+	timestamps := make(map[int64]bool, len(first))
+	for _, sample := range first {
+		timestamps[sample.Timestamp] = true
+	}
+
+	merged := first
+	for _, sample := range second {
+		if timestamps[sample.Timestamp] {
+			e.addDroppedSamples(1, "duplicate_series")
+			continue
+		}
+		timestamps[sample.Timestamp] = true
+		merged = append(merged, sample)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp < merged[j].Timestamp
+	})
+	return merged
+}
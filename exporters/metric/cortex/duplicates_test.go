@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestCheckDuplicateSeries checks whether series that an instrument registered by two
+// instrumentation libraries produces are merged or fail the push depending on
+// DuplicateScopePolicy.
+func TestCheckDuplicateSeries(t *testing.T) {
+	tests := []struct {
+		testName      string
+		policy        string
+		wantSamples   []prompb.Sample
+		wantCounters  map[string]float64
+		expectedError error
+	}{
+		{
+			testName:      "Error",
+			policy:        DuplicateScopePolicyError,
+			expectedError: ErrDuplicateSeries,
+		},
+		{
+			testName: "Merge",
+			policy:   DuplicateScopePolicyMerge,
+			wantSamples: []prompb.Sample{
+				{Value: 1, Timestamp: 1000},
+				{Value: 2, Timestamp: 2000},
+			},
+			wantCounters: map[string]float64{
+				"cortex_exporter_dropped_samples_total{reason=duplicate_series}": 1,
+			},
+		},
+	}
+
+	libraryA := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind, metric.WithInstrumentationName("library_a"))
+	libraryB := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind, metric.WithInstrumentationName("library_b"))
+	records := []export.Record{
+		newSumRecord(t, &libraryA, 1, time.Time{}, time.Unix(1, 0)),
+		newSumRecord(t, &libraryB, 2, time.Time{}, time.Unix(2, 0)),
+		newSumRecord(t, &libraryB, 3, time.Time{}, time.Unix(1, 0)),
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					DuplicateScopePolicy: test.policy,
+					MeterProvider:        controller.Provider(),
+				},
+			}
+
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 3)
+
+			timeSeries, err = exporter.checkDuplicateSeries(timeSeries)
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 1)
+			require.Equal(t, test.wantSamples, timeSeries[0].Samples)
+			require.Equal(t, test.wantCounters, selfMetricValues(t, controller))
+		})
+	}
+}
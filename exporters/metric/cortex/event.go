@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"time"
+)
+
+// PushEvent summarizes a push to Cortex. It is published on Config.EventChan after
+// every push.
+type PushEvent struct {
+	ExportResult
+
+	// Duration is how long the push took, including conversion and retries.
+	Duration time.Duration
+
+	// Err is the error the push failed with, or nil if it succeeded.
+	Err error
+}
+
+// publishEvent publishes a PushEvent on EventChan if it is set. Events are dropped
+// instead of blocking the push when the channel is not ready to receive.
+func (e *Exporter) publishEvent(result ExportResult, duration time.Duration, err error) {
+	if e.config.EventChan == nil {
+		return
+	}
+
+	select {
+	case e.config.EventChan <- PushEvent{ExportResult: result, Duration: duration, Err: err}:
+	default:
+	}
+}
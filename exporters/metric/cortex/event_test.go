@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEventChan checks whether a PushEvent is published after every push, and whether
+// pushes do not block when nobody receives the events.
+func TestEventChan(t *testing.T) {
+	fail := false
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		if fail {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	events := make(chan PushEvent, 1)
+	exporter := Exporter{
+		config: Config{
+			Endpoint:  server.URL,
+			EventChan: events,
+		},
+	}
+
+	// A successful push.
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	event := <-events
+	require.Equal(t, 1, event.SeriesSent)
+	require.NotZero(t, event.BytesSent)
+	require.Equal(t, http.StatusOK, event.StatusCode)
+	require.NotZero(t, event.Duration)
+	require.Nil(t, event.Err)
+
+	// A failed push.
+	fail = true
+	err := exporter.Export(context.Background(), getSumCheckpoint(t, 1))
+	require.Equal(t, fmt.Errorf("500 Internal Server Error"), err)
+	event = <-events
+	require.Equal(t, http.StatusInternalServerError, event.StatusCode)
+	require.Equal(t, err, event.Err)
+
+	// The event of the second push is dropped since the channel is full.
+	fail = false
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Len(t, events, 1)
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"regexp"
+	"sync"
+)
+
+// metricNameFilterCache maps the patterns of MetricNameAllowlist and MetricNameDenylist
+// to their compiled regular expressions, so that the patterns checked by Validate are
+// not compiled again when the Exporter is created.
+var metricNameFilterCache sync.Map
+
+// compileMetricNameFilter compiles the patterns of MetricNameAllowlist or
+// MetricNameDenylist. Like MetricNameSchema, a pattern has to match the whole metric name.
+func compileMetricNameFilter(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		if cached, ok := metricNameFilterCache.Load(pattern); ok {
+			regexps[i] = cached.(*regexp.Regexp)
+			continue
+		}
+		regex, err := regexp.Compile(anchorMetricNameSchema(pattern))
+		if err != nil {
+			return nil, err
+		}
+		metricNameFilterCache.Store(pattern, regex)
+		regexps[i] = regex
+	}
+	return regexps, nil
+}
+
+// compileMetricNameFilters sets the compiled MetricNameAllowlist and MetricNameDenylist
+// when the Exporter is created. Validate has already compiled and cached them.
+func (e *Exporter) compileMetricNameFilters() error {
+	var err error
+	if e.allowRegexs, err = compileMetricNameFilter(e.config.MetricNameAllowlist); err != nil {
+		return ErrInvalidMetricNameFilter
+	}
+	if e.denyRegexs, err = compileMetricNameFilter(e.config.MetricNameDenylist); err != nil {
+		return ErrInvalidMetricNameFilter
+	}
+	return nil
+}
+
+// keepMetricName reports whether a metric is converted according to MetricNameAllowlist
+// and MetricNameDenylist. The allowlist is applied first: when it is set, only metrics
+// matching one of its patterns are kept. Metrics matching a pattern of the denylist are
+// removed from those.
+func (e *Exporter) keepMetricName(name string) bool {
+	if len(e.config.MetricNameAllowlist) > 0 && !matchesAny(e.allowRegexs, name) {
+		return false
+	}
+	return !matchesAny(e.denyRegexs, name)
+}
+
+// matchesAny reports whether a name matches one of the regular expressions.
+func matchesAny(regexps []*regexp.Regexp, name string) bool {
+	for _, regex := range regexps {
+		if regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/metric"
+)
+
+// TestMetricNameFilter checks whether metrics are converted according to
+// MetricNameAllowlist and MetricNameDenylist, with the allowlist applied first.
+func TestMetricNameFilter(t *testing.T) {
+	names := []string{"http.requests", "http_errors", "runtime.go.goroutines", "app_latency"}
+
+	tests := []struct {
+		testName  string
+		allowlist []string
+		denylist  []string
+		wantNames []string
+	}{
+		{
+			testName:  "No filter",
+			wantNames: []string{"http_requests", "http_errors", "runtime_go_goroutines", "app_latency"},
+		},
+		{
+			testName:  "Allowlist",
+			allowlist: []string{"http_.*", "app_latency"},
+			wantNames: []string{"http_requests", "http_errors", "app_latency"},
+		},
+		{
+			testName:  "Denylist",
+			denylist:  []string{"runtime_.*"},
+			wantNames: []string{"http_requests", "http_errors", "app_latency"},
+		},
+		{
+			testName:  "Allowlist then denylist",
+			allowlist: []string{"http_.*"},
+			denylist:  []string{"http_errors"},
+			wantNames: []string{"http_requests"},
+		},
+		{
+			testName:  "Patterns match the whole name",
+			allowlist: []string{"http"},
+			wantNames: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{
+				config: Config{
+					MetricNameAllowlist: test.allowlist,
+					MetricNameDenylist:  test.denylist,
+				},
+			}
+			require.Nil(t, exporter.compileMetricNameFilters())
+
+			var gotNames []string
+			for _, name := range names {
+				desc := metric.NewDescriptor(name, metric.CounterKind, metric.Int64NumberKind)
+				timeSeries, err := exporter.convertRecord(newSumRecord(t, &desc, 1, time.Time{}, time.Now()))
+				require.Nil(t, err)
+				for _, ts := range timeSeries {
+					gotNames = append(gotNames, metricName(ts))
+				}
+			}
+			require.Equal(t, test.wantNames, gotNames)
+		})
+	}
+}
+
+// TestMetricNameFilterCache checks whether a pattern compiled by Validate is not
+// compiled again when the Exporter is created.
+func TestMetricNameFilterCache(t *testing.T) {
+	config := Config{
+		Endpoint:            "http://localhost:9009/api/prom/push",
+		MetricNameAllowlist: []string{"cached_allow_.*"},
+		MetricNameDenylist:  []string{"cached_deny_.*"},
+	}
+	require.Nil(t, config.Validate())
+	allow, ok := metricNameFilterCache.Load("cached_allow_.*")
+	require.True(t, ok)
+	deny, ok := metricNameFilterCache.Load("cached_deny_.*")
+	require.True(t, ok)
+
+	exporter, err := NewRawExporter(config)
+	require.Nil(t, err)
+	require.True(t, allow == exporter.allowRegexs[0])
+	require.True(t, deny == exporter.denyRegexs[0])
+}
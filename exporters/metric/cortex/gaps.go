@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// addGapMarkers adds a staleness marker for every series that was in the previous push
+// but is missing from this one, if mark is set. PromQL does not interpolate across a
+// staleness marker, so intermittent series show a gap instead of their last value until
+// they are sent again. A marker is only sent once for every gap. The series of the push
+// are remembered either way.
+func (e *Exporter) addGapMarkers(timeSeries []*prompb.TimeSeries, now time.Time, mark bool) []*prompb.TimeSeries {
+	present := make(map[string][]*prompb.Label, len(timeSeries))
+	for _, ts := range timeSeries {
+		present[seriesKey(ts.Labels)] = ts.Labels
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+	for key, labels := range e.presentSeries {
+		if _, found := present[key]; found || !mark {
+			continue
+		}
+		timeSeries = append(timeSeries, &prompb.TimeSeries{
+			Labels: labels,
+			Samples: []prompb.Sample{{
+				Value:     math.Float64frombits(value.StaleNaN),
+				Timestamp: timestamp,
+			}},
+		})
+	}
+	e.presentSeries = present
+	return timeSeries
+}
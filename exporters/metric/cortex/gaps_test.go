@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddGapMarkers checks whether a staleness marker is added once for a series that is
+// missing from a push after being in the previous one.
+func TestAddGapMarkers(t *testing.T) {
+	exporter := Exporter{config: Config{EmitGapMarkers: true}}
+	series := func(names ...string) []*prompb.TimeSeries {
+		var timeSeries []*prompb.TimeSeries
+		for _, name := range names {
+			timeSeries = append(timeSeries, &prompb.TimeSeries{
+				Labels:  []*prompb.Label{{Name: "__name__", Value: name}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+			})
+		}
+		return timeSeries
+	}
+	now := time.Unix(10, 0)
+
+	got := exporter.addGapMarkers(series("a", "b"), now, true)
+	require.Len(t, got, 2)
+
+	// b is missing, so a marker is sent for it.
+	got = exporter.addGapMarkers(series("a"), now, true)
+	require.Len(t, got, 2)
+	require.Equal(t, "b", metricName(got[1]))
+	require.Len(t, got[1].Samples, 1)
+	require.True(t, value.IsStaleNaN(got[1].Samples[0].Value))
+	require.Equal(t, int64(10000), got[1].Samples[0].Timestamp)
+
+	// The gap was already marked.
+	got = exporter.addGapMarkers(series("a"), now, true)
+	require.Len(t, got, 1)
+
+	// b is sent again after the gap.
+	got = exporter.addGapMarkers(series("a", "b"), now, true)
+	require.Len(t, got, 2)
+	require.Equal(t, float64(1), got[1].Samples[0].Value)
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"math"
+
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+)
+
+const (
+	// HistogramBucketPolicyDrop drops a histogram whose cumulative bucket counts are not
+	// monotonic.
+	HistogramBucketPolicyDrop = "drop"
+
+	// HistogramBucketPolicyRepair sends a histogram whose cumulative bucket counts are
+	// not monotonic with the offending bucket counts set to zero, so that the cumulative
+	// count stays flat instead of decreasing.
+	HistogramBucketPolicyRepair = "repair"
+)
+
+// repairedHistogram is a Histogram aggregation with replaced buckets.
+type repairedHistogram struct {
+	histogram aggregation.Histogram
+	buckets   aggregation.Buckets
+}
+
+// Kind returns the Kind of the original aggregation.
+func (h repairedHistogram) Kind() aggregation.Kind {
+	return h.histogram.Kind()
+}
+
+// Sum returns the sum of the original aggregation.
+func (h repairedHistogram) Sum() (apimetric.Number, error) {
+	return h.histogram.Sum()
+}
+
+// Histogram returns the replaced buckets.
+func (h repairedHistogram) Histogram() (aggregation.Buckets, error) {
+	return h.buckets, nil
+}
+
+// checkHistogramBuckets checks whether the cumulative bucket counts of a histogram are
+// monotonic and handles violations according to HistogramBucketPolicy. The buckets hold
+// the count of each bucket, so the cumulative counts decrease where a count is negative
+// and are invalid where it is NaN. It returns false if the histogram is dropped.
+func (e *Exporter) checkHistogramBuckets(record metric.Record, histogram aggregation.Histogram) (aggregation.Histogram, bool, error) {
+	buckets, err := histogram.Histogram()
+	if err != nil {
+		return nil, false, err
+	}
+
+	valid := true
+	for _, count := range buckets.Counts {
+		if count < 0 || math.IsNaN(count) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return histogram, true, nil
+	}
+
+	name := record.Descriptor().Name()
+	if e.config.HistogramBucketPolicy == HistogramBucketPolicyDrop {
+		e.logf("Histogram %s has non-monotonic bucket counts. It is dropped.", name)
+		// A histogram is converted to a series for every bucket, the sum, and the count.
+		for i := 0; i < len(buckets.Counts)+2; i++ {
+			e.addDroppedSeries("non_monotonic_buckets")
+		}
+		return nil, false, nil
+	}
+
+	e.logf("Histogram %s has non-monotonic bucket counts. They are repaired.", name)
+	counts := make([]float64, len(buckets.Counts))
+	for i, count := range buckets.Counts {
+		if count > 0 {
+			counts[i] = count
+		}
+	}
+	repaired := repairedHistogram{
+		histogram: histogram,
+		buckets:   aggregation.Buckets{Boundaries: buckets.Boundaries, Counts: counts},
+	}
+	return repaired, true, nil
+}
@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/label"
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+)
+
+// bucketsHistogram is a Histogram aggregation with fixed buckets, which can hold counts
+// the histogram aggregator never produces.
+type bucketsHistogram struct {
+	buckets aggregation.Buckets
+}
+
+func (h bucketsHistogram) Kind() aggregation.Kind {
+	return aggregation.HistogramKind
+}
+
+func (h bucketsHistogram) Sum() (metric.Number, error) {
+	return metric.NewFloat64Number(10), nil
+}
+
+func (h bucketsHistogram) Histogram() (aggregation.Buckets, error) {
+	return h.buckets, nil
+}
+
+// TestHistogramBucketPolicy checks whether histograms whose cumulative bucket counts
+// decrease are sent unchanged, dropped, or repaired according to HistogramBucketPolicy.
+func TestHistogramBucketPolicy(t *testing.T) {
+	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Float64NumberKind)
+	labels := label.NewSet()
+	// The cumulative counts are 2, 1, and 4.
+	record := export.NewRecord(&desc, &labels, testResource, bucketsHistogram{
+		buckets: aggregation.Buckets{Boundaries: []float64{1, 2}, Counts: []float64{2, -1, 3}},
+	}, time.Time{}, time.Now())
+
+	tests := []struct {
+		testName    string
+		policy      string
+		wantValues  map[string]float64
+		wantDropped float64
+	}{
+		{
+			testName: "Not checked",
+			policy:   "",
+			wantValues: map[string]float64{
+				"metric_name_sum":      10,
+				"metric_name{le=1}":    2,
+				"metric_name{le=2}":    1,
+				"metric_name{le=+Inf}": 4,
+				"metric_name_count":    4,
+			},
+		},
+		{
+			testName:    "Drop",
+			policy:      HistogramBucketPolicyDrop,
+			wantValues:  map[string]float64{},
+			wantDropped: 5,
+		},
+		{
+			testName: "Repair",
+			policy:   HistogramBucketPolicyRepair,
+			wantValues: map[string]float64{
+				"metric_name_sum":      10,
+				"metric_name{le=1}":    2,
+				"metric_name{le=2}":    2,
+				"metric_name{le=+Inf}": 5,
+				"metric_name_count":    5,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					HistogramBucketPolicy: test.policy,
+					MeterProvider:         controller.Provider(),
+				},
+			}
+
+			timeSeries, err := exporter.convertRecord(record)
+			require.Nil(t, err)
+			require.Equal(t, test.wantValues, timeSeriesValues(timeSeries))
+			require.Equal(t, test.wantDropped, selfMetricValues(t, controller)["cortex_exporter_dropped_series_total{reason=non_monotonic_buckets}"])
+		})
+	}
+}
+
+// TestHistogramBucketPolicyValid checks whether histograms with monotonic cumulative
+// bucket counts are left unchanged.
+func TestHistogramBucketPolicyValid(t *testing.T) {
+	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Float64NumberKind)
+	record := newHistogramRecord(t, &desc, []float64{1, 2}, time.Now(), 0.5, 1.5, 3)
+
+	exporter := Exporter{config: Config{HistogramBucketPolicy: HistogramBucketPolicyDrop}}
+	timeSeries, err := exporter.convertRecord(record)
+	require.Nil(t, err)
+	require.Equal(t, map[string]float64{
+		"metric_name_sum":      5,
+		"metric_name{le=1}":    1,
+		"metric_name{le=2}":    2,
+		"metric_name{le=+Inf}": 3,
+		"metric_name_count":    3,
+	}, timeSeriesValues(timeSeries))
+}
@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+)
+
+// histogramMerger merges the TimeSeries of histograms with the same name and labels, which
+// occur when overlapping views produce more than one histogram for a series in one
+// collection. Without merging, Cortex would receive conflicting samples for the series.
+type histogramMerger struct {
+	exporter *Exporter
+
+	// series holds the TimeSeries of the histograms converted so far by their series key.
+	series map[string]*prompb.TimeSeries
+}
+
+// add appends the TimeSeries converted from a record to timeSeries. The TimeSeries of a
+// histogram whose series were already converted from another histogram are merged into
+// them bucket-wise instead: samples with the same timestamp are added up, except for
+// "_created" samples, of which the earliest is kept.
+func (m *histogramMerger) add(timeSeries []*prompb.TimeSeries, record metric.Record, converted []*prompb.TimeSeries) []*prompb.TimeSeries {
+	if _, ok := record.Aggregation().(aggregation.Histogram); !ok {
+		return append(timeSeries, converted...)
+	}
+	if m.series == nil {
+		m.series = make(map[string]*prompb.TimeSeries)
+	}
+
+	merged, added := 0, 0
+	for _, ts := range converted {
+		key := seriesKey(ts.Labels)
+		existing, found := m.series[key]
+		if !found {
+			m.series[key] = ts
+			timeSeries = append(timeSeries, ts)
+			added++
+			continue
+		}
+		mergeHistogramSamples(existing, ts.Samples)
+		merged++
+	}
+
+	// A histogram that only shares some of its series with another one has different
+	// bucket boundaries, so its cumulative buckets cannot be added up correctly.
+	if merged > 0 && added > 0 {
+		m.exporter.logf("Histograms of %s have different bucket boundaries and cannot be merged correctly", record.Descriptor().Name())
+	}
+	return timeSeries
+}
+
+// mergeHistogramSamples merges samples into the samples of a TimeSeries with the same
+// labels. Samples whose timestamp the TimeSeries does not have are appended.
+func mergeHistogramSamples(ts *prompb.TimeSeries, samples []prompb.Sample) {
+	created := strings.HasSuffix(metricName(ts), "_created")
+	for _, sample := range samples {
+		found := false
+		for i := range ts.Samples {
+			if ts.Samples[i].Timestamp != sample.Timestamp {
+				continue
+			}
+			found = true
+			if !created {
+				ts.Samples[i].Value += sample.Value
+			} else if sample.Value < ts.Samples[i].Value {
+				ts.Samples[i].Value = sample.Value
+			}
+		}
+		if !found {
+			ts.Samples = append(ts.Samples, sample)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestMergeDuplicateHistograms checks whether two histograms with the same name and labels
+// are merged bucket-wise into a single set of series.
+func TestMergeDuplicateHistograms(t *testing.T) {
+	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Float64NumberKind)
+	end := time.Unix(100, 0)
+	records := []export.Record{
+		newHistogramRecord(t, &desc, []float64{1, 5}, end, 0.5, 3),
+		newHistogramRecord(t, &desc, []float64{1, 5}, end, 0.5, 10),
+	}
+
+	for _, concurrency := range []int{0, 2} {
+		exporter := Exporter{
+			config: Config{
+				MergeDuplicateHistograms: true,
+				ConvertConcurrency:       concurrency,
+			},
+		}
+		timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+		require.Nil(t, err)
+		require.Len(t, timeSeries, 5)
+		require.Equal(t, map[string]float64{
+			"metric_name_sum":      14,
+			"metric_name_count":    4,
+			"metric_name{le=1}":    2,
+			"metric_name{le=5}":    3,
+			"metric_name{le=+Inf}": 4,
+		}, timeSeriesValues(timeSeries))
+	}
+}
+
+// TestMergeHistogramsWithDifferentBoundaries checks whether a warning is logged when
+// histograms of the same series have different bucket boundaries.
+func TestMergeHistogramsWithDifferentBoundaries(t *testing.T) {
+	var logs bytes.Buffer
+	exporter := Exporter{
+		config: Config{
+			MergeDuplicateHistograms: true,
+			Logger:                   log.New(&logs, "", 0),
+		},
+	}
+
+	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Float64NumberKind)
+	records := []export.Record{
+		newHistogramRecord(t, &desc, []float64{1, 5}, time.Unix(100, 0), 0.5),
+		newHistogramRecord(t, &desc, []float64{2}, time.Unix(100, 0), 0.5),
+	}
+	_, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+	require.Nil(t, err)
+	require.Equal(t, "Histograms of metric_name have different bucket boundaries and cannot be merged correctly\n", logs.String())
+}
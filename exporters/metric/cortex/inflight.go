@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// InFlightPolicyWait makes a request wait until the number of in-flight requests
+	// drops below MaxInFlightRequests.
+	InFlightPolicyWait = "wait"
+
+	// InFlightPolicySkip makes a request fail with ErrTooManyInFlightRequests when
+	// MaxInFlightRequests requests are already in flight.
+	InFlightPolicySkip = "skip"
+)
+
+var (
+	// ErrTooManyInFlightRequests occurs when a request is skipped because
+	// MaxInFlightRequests requests are already in flight.
+	ErrTooManyInFlightRequests = fmt.Errorf("Too many requests in flight")
+)
+
+// acquireInFlight reserves a slot for a request when MaxInFlightRequests is set. The
+// returned function releases the slot and must be called once the request is done.
+func (e *Exporter) acquireInFlight(ctx context.Context) (func(), error) {
+	if e.config.MaxInFlightRequests <= 0 {
+		return func() {}, nil
+	}
+
+	e.inFlightOnce.Do(func() {
+		e.inFlight = make(chan struct{}, e.config.MaxInFlightRequests)
+	})
+	release := func() { <-e.inFlight }
+
+	if e.config.InFlightPolicy == InFlightPolicySkip {
+		select {
+		case e.inFlight <- struct{}{}:
+			return release, nil
+		default:
+			return nil, ErrTooManyInFlightRequests
+		}
+	}
+
+	select {
+	case e.inFlight <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMaxInFlightRequests checks whether concurrent sends never exceed
+// MaxInFlightRequests when waiting for a free slot.
+func TestMaxInFlightRequests(t *testing.T) {
+	var current, max int32
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:            server.URL,
+			MaxInFlightRequests: 2,
+			InFlightPolicy:      InFlightPolicyWait,
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, exporter.send(context.Background(), []byte("message"), &ExportResult{}))
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, atomic.LoadInt32(&max), int32(2))
+}
+
+// TestInFlightPolicySkip checks whether a send fails with ErrTooManyInFlightRequests
+// instead of waiting when all slots are taken.
+func TestInFlightPolicySkip(t *testing.T) {
+	started := make(chan struct{})
+	block := make(chan struct{})
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-block
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:            server.URL,
+			MaxInFlightRequests: 1,
+			InFlightPolicy:      InFlightPolicySkip,
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- exporter.send(context.Background(), []byte("message"), &ExportResult{})
+	}()
+
+	// Wait for the first request to take the only slot.
+	<-started
+
+	err := exporter.send(context.Background(), []byte("message"), &ExportResult{})
+	require.Equal(t, ErrTooManyInFlightRequests, err)
+
+	close(block)
+	require.Nil(t, <-done)
+}
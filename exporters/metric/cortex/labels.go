@@ -0,0 +1,210 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/label"
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	// LabelSourceSeries refers to the labels recorded with a measurement.
+	LabelSourceSeries = "series"
+
+	// LabelSourceResource refers to the labels of the Resource of a record.
+	LabelSourceResource = "resource"
+
+	// LabelSourceExternal refers to the ExternalLabels in the Config.
+	LabelSourceExternal = "external"
+)
+
+// defaultLabelPrecedence is used when LabelPrecedence is not set.
+var defaultLabelPrecedence = []string{LabelSourceSeries, LabelSourceResource, LabelSourceExternal}
+
+// validLabelPrecedence returns whether precedence holds every label source exactly once.
+func validLabelPrecedence(precedence []string) bool {
+	if len(precedence) != len(defaultLabelPrecedence) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, source := range precedence {
+		if source != LabelSourceSeries && source != LabelSourceResource && source != LabelSourceExternal {
+			return false
+		}
+		if seen[source] {
+			return false
+		}
+		seen[source] = true
+	}
+	return true
+}
+
+// mergeLabels returns a copy of a record whose labels are its series labels, its
+// resource labels, and the ExternalLabels merged according to LabelPrecedence. The
+// copy has an empty resource, so its labels are used as they are during conversion.
+func (e *Exporter) mergeLabels(record metric.Record) metric.Record {
+	// The default precedence without external labels is the order in which the
+	// conversion merges series and resource labels itself.
+	if e.config.ExternalLabels == nil && e.config.LabelPrecedence == nil {
+		return record
+	}
+
+	precedence := e.config.LabelPrecedence
+	if precedence == nil {
+		precedence = defaultLabelPrecedence
+	}
+
+	// Add the sources from lowest to highest precedence so that labels of sources with
+	// a higher precedence replace the ones added before.
+	merged := map[kv.Key]kv.Value{}
+	for i := len(precedence) - 1; i >= 0; i-- {
+		switch precedence[i] {
+		case LabelSourceSeries:
+			addLabels(merged, record.Labels().Iter())
+		case LabelSourceResource:
+			addLabels(merged, record.Resource().Iter())
+		case LabelSourceExternal:
+			for name, value := range e.config.ExternalLabels {
+				merged[kv.Key(name)] = kv.StringValue(value)
+			}
+		}
+	}
+
+	kvs := make([]kv.KeyValue, 0, len(merged))
+	for key, value := range merged {
+		kvs = append(kvs, kv.KeyValue{Key: key, Value: value})
+	}
+	labels := label.NewSet(kvs...)
+	return metric.NewRecord(record.Descriptor(), &labels, resource.Empty(), record.Aggregation(), record.StartTime(), record.EndTime())
+}
+
+// collapseAttributes returns a copy of a record whose series and resource labels are
+// replaced by a single "attributes" label holding them as a JSON object. Records without
+// labels are returned as they are.
+func collapseAttributes(record metric.Record) metric.Record {
+	attributes := map[string]string{}
+	mi := label.NewMergeIterator(record.Labels(), record.Resource().LabelSet())
+	for mi.Next() {
+		l := mi.Label()
+		attributes[string(l.Key)] = l.Value.Emit()
+	}
+	if len(attributes) == 0 {
+		return record
+	}
+
+	// Keys of maps are encoded in sorted order, so equal attribute sets always result in
+	// the same label value. Encoding a map of strings cannot fail.
+	encoded, _ := json.Marshal(attributes)
+	labels := label.NewSet(kv.String("attributes", string(encoded)))
+	return metric.NewRecord(record.Descriptor(), &labels, resource.Empty(), record.Aggregation(), record.StartTime(), record.EndTime())
+}
+
+// addLabels adds the labels of an iterator to a map, replacing existing labels.
+func addLabels(labels map[kv.Key]kv.Value, iter label.Iterator) {
+	for iter.Next() {
+		l := iter.Label()
+		labels[l.Key] = l.Value
+	}
+}
+
+// setLabel sets a label on every TimeSeries, replacing the value of an existing label
+// with the same name.
+func setLabel(timeSeries []*prompb.TimeSeries, name, value string) {
+	for _, ts := range timeSeries {
+		found := false
+		for _, label := range ts.Labels {
+			if label.Name == name {
+				label.Value = value
+				found = true
+			}
+		}
+		if !found {
+			ts.Labels = append(ts.Labels, &prompb.Label{Name: name, Value: value})
+		}
+	}
+}
+
+// dropEmptyLabels removes labels with an empty value from every TimeSeries.
+func dropEmptyLabels(timeSeries []*prompb.TimeSeries) {
+	for _, ts := range timeSeries {
+		labels := ts.Labels[:0]
+		for _, label := range ts.Labels {
+			if label.Value != "" {
+				labels = append(labels, label)
+			}
+		}
+		ts.Labels = labels
+	}
+}
+
+// addMissingLabels adds a label with its default value to every TimeSeries that does not
+// have it. Labels the TimeSeries already has keep their value.
+func addMissingLabels(timeSeries []*prompb.TimeSeries, defaults map[string]string) {
+	// The labels are added in sorted order so that the result does not depend on the
+	// iteration order of the map.
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, ts := range timeSeries {
+		for _, name := range names {
+			if !hasLabel(ts.Labels, name) {
+				ts.Labels = append(ts.Labels, &prompb.Label{Name: name, Value: defaults[name]})
+			}
+		}
+	}
+}
+
+// addExternalLabels adds the ExternalLabels to TimeSeries the Exporter creates itself,
+// like the build info series. Converted TimeSeries get them from mergeLabels instead.
+// Labels the TimeSeries already has keep their value.
+func (e *Exporter) addExternalLabels(timeSeries []*prompb.TimeSeries) {
+	external := make(map[string]string, len(e.config.ExternalLabels))
+	for name, value := range e.config.ExternalLabels {
+		external[sanitize(name)] = value
+	}
+	addMissingLabels(timeSeries, external)
+	sortLabels(timeSeries)
+}
+
+// sortLabels sorts the labels of every TimeSeries by name, as the remote write
+// specification requires.
+func sortLabels(timeSeries []*prompb.TimeSeries) {
+	for _, ts := range timeSeries {
+		labels := ts.Labels
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].Name < labels[j].Name
+		})
+	}
+}
+
+// hasLabel returns whether labels contain a label with the given name.
+func hasLabel(labels []*prompb.Label, name string) bool {
+	for _, label := range labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestShardLabel checks whether the shard label is added to every series the Exporter
+// sends, including series created by the Exporter itself.
+func TestShardLabel(t *testing.T) {
+	var received prompb.WriteRequest
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		decoded, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		received = *decoded
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			EmitBuildInfo: true,
+			ShardLabel: map[string]string{
+				"name":  "shard",
+				"value": "2",
+			},
+		},
+	}
+	require.Nil(t, exporter.Export(context.Background(), getHistogramCheckpoint(t)))
+	require.NotEmpty(t, received.Timeseries)
+
+	for _, ts := range received.Timeseries {
+		require.Contains(t, ts.Labels, &prompb.Label{Name: "shard", Value: "2"})
+	}
+}
+
+// TestSetLabel checks whether setLabel adds missing labels and replaces existing ones.
+func TestSetLabel(t *testing.T) {
+	timeSeries := []*prompb.TimeSeries{
+		{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+			},
+		},
+		{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "shard", Value: "1"},
+			},
+		},
+	}
+
+	setLabel(timeSeries, "shard", "2")
+
+	for _, ts := range timeSeries {
+		require.Equal(t, []*prompb.Label{
+			{Name: "__name__", Value: "metric_name"},
+			{Name: "shard", Value: "2"},
+		}, ts.Labels)
+	}
+}
+
+// TestLabelPrecedence checks whether LabelPrecedence decides which label wins when a
+// series label, a resource label, and an external label have the same name.
+func TestLabelPrecedence(t *testing.T) {
+	tests := []struct {
+		testName   string
+		precedence []string
+		wantValue  string
+	}{
+		{
+			testName:   "Default precedence",
+			precedence: nil,
+			wantValue:  "series",
+		},
+		{
+			testName:   "Resource labels first",
+			precedence: []string{LabelSourceResource, LabelSourceSeries, LabelSourceExternal},
+			wantValue:  "resource",
+		},
+		{
+			testName:   "External labels first",
+			precedence: []string{LabelSourceExternal, LabelSourceSeries, LabelSourceResource},
+			wantValue:  "external",
+		},
+	}
+
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("env", "series"))
+	record = export.NewRecord(&desc, record.Labels(), resource.New(kv.String("env", "resource")), record.Aggregation(), record.StartTime(), record.EndTime())
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{
+				config: Config{
+					ExternalLabels: map[string]string{
+						"env":     "external",
+						"cluster": "east",
+					},
+					LabelPrecedence: test.precedence,
+				},
+			}
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 1)
+
+			require.ElementsMatch(t, []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "env", Value: test.wantValue},
+				{Name: "cluster", Value: "east"},
+			}, timeSeries[0].Labels)
+		})
+	}
+}
+
+// TestExternalLabels checks whether the ExternalLabels are added to every series the
+// Exporter sends, including series created by the Exporter itself, without replacing
+// labels of the series and with the labels sorted by name.
+func TestExternalLabels(t *testing.T) {
+	var writeRequest prompb.WriteRequest
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		decoded, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		writeRequest = *decoded
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			EmitBuildInfo: true,
+			ExternalLabels: map[string]string{
+				"cluster":      "east",
+				"R":            "external",
+				"version":      "external",
+				"replica.name": "a",
+			},
+		},
+	}
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Len(t, writeRequest.Timeseries, 2)
+
+	require.Equal(t, []*prompb.Label{
+		{Name: "R", Value: "V"},
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "cluster", Value: "east"},
+		{Name: "replica_name", Value: "a"},
+		{Name: "version", Value: "external"},
+	}, writeRequest.Timeseries[0].Labels)
+
+	buildInfo := writeRequest.Timeseries[1].Labels
+	require.Equal(t, buildInfoName, buildInfo[1].Value)
+	require.True(t, sort.SliceIsSorted(buildInfo, func(i, j int) bool {
+		return buildInfo[i].Name < buildInfo[j].Name
+	}))
+	require.Contains(t, buildInfo, &prompb.Label{Name: "cluster", Value: "east"})
+	require.Contains(t, buildInfo, &prompb.Label{Name: "replica_name", Value: "a"})
+	require.Contains(t, buildInfo, &prompb.Label{Name: "version", Value: moduleVersion()})
+}
+
+// TestCollapseAttributesToJSON checks whether series and resource labels are collapsed
+// into a single label holding a JSON object.
+func TestCollapseAttributesToJSON(t *testing.T) {
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("user", "u1"), kv.Int("shard", 7))
+	record = export.NewRecord(&desc, record.Labels(), resource.New(kv.String("host", "h1")), record.Aggregation(), record.StartTime(), record.EndTime())
+
+	exporter := Exporter{config: Config{CollapseAttributesToJSON: true}}
+	timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+	require.Nil(t, err)
+	require.Len(t, timeSeries, 1)
+
+	require.ElementsMatch(t, []*prompb.Label{
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "attributes", Value: `{"host":"h1","shard":"7","user":"u1"}`},
+	}, timeSeries[0].Labels)
+}
+
+// TestLabelTransform checks whether LabelTransform is applied to the labels of every
+// converted series.
+func TestLabelTransform(t *testing.T) {
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("service", "checkout"))
+
+	exporter := Exporter{
+		config: Config{
+			LabelTransform: func(labels []*prompb.Label) []*prompb.Label {
+				for _, l := range labels {
+					if l.Name == "service" {
+						return append(labels, &prompb.Label{Name: "team", Value: "team-" + l.Value})
+					}
+				}
+				return labels
+			},
+		},
+	}
+	timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+	require.Nil(t, err)
+	require.Len(t, timeSeries, 1)
+
+	require.ElementsMatch(t, []*prompb.Label{
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "R", Value: "V"},
+		{Name: "service", Value: "checkout"},
+		{Name: "team", Value: "team-checkout"},
+	}, timeSeries[0].Labels)
+}
+
+// TestDropEmptyLabels checks whether labels with an empty value are removed during
+// conversion unless DropEmptyLabels is disabled.
+func TestDropEmptyLabels(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		testName        string
+		dropEmptyLabels *bool
+		wantLabels      []*prompb.Label
+	}{
+		{
+			testName: "Empty labels are dropped by default",
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "user", Value: "u1"},
+			},
+		},
+		{
+			testName:        "Empty labels are kept when disabled",
+			dropEmptyLabels: &disabled,
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "user", Value: "u1"},
+				{Name: "tenant", Value: ""},
+			},
+		},
+	}
+
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("user", "u1"), kv.String("tenant", ""))
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{config: Config{DropEmptyLabels: test.dropEmptyLabels}}
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 1)
+			require.ElementsMatch(t, test.wantLabels, timeSeries[0].Labels)
+		})
+	}
+}
+
+// TestRequiredLabelDefaults checks whether a default value is added for required labels
+// that a series does not have, without replacing the values of labels it has.
+func TestRequiredLabelDefaults(t *testing.T) {
+	tests := []struct {
+		testName   string
+		labels     []kv.KeyValue
+		wantLabels []*prompb.Label
+	}{
+		{
+			testName: "Missing label is added",
+			labels:   []kv.KeyValue{kv.String("R", "V")},
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "job", Value: "unknown"},
+			},
+		},
+		{
+			testName: "Present label is kept",
+			labels:   []kv.KeyValue{kv.String("R", "V"), kv.String("job", "api")},
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+				{Name: "job", Value: "api"},
+			},
+		},
+	}
+
+	desc := apimetric.NewDescriptor("metric_name", apimetric.CounterKind, apimetric.Int64NumberKind)
+	exporter := Exporter{
+		config: Config{
+			RequiredLabelDefaults: map[string]string{"job": "unknown"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, test.labels...)
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+			require.Nil(t, err)
+			require.Len(t, timeSeries, 1)
+			require.ElementsMatch(t, test.wantLabels, timeSeries[0].Labels)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// LatencyStats summarizes how long the most recent pushes took. The number of pushes it
+// covers is set with LatencyWindow.
+type LatencyStats struct {
+	// Pushes is the number of pushes in the window.
+	Pushes int
+
+	// Min and Max are the shortest and longest duration of a push in the window.
+	Min time.Duration
+	Max time.Duration
+
+	// P99 is the 99th percentile of the durations of the pushes in the window.
+	P99 time.Duration
+}
+
+// PushLatency returns statistics on how long the last LatencyWindow pushes took, including
+// conversion and retries. It returns a zero LatencyStats if LatencyWindow is not set or no
+// push happened yet.
+func (e *Exporter) PushLatency() LatencyStats {
+	e.lock.Lock()
+	durations := make([]time.Duration, len(e.latencies))
+	copy(durations, e.latencies)
+	e.lock.Unlock()
+
+	if len(durations) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	// The percentile uses the nearest-rank method, so that it is always the duration of
+	// an actual push.
+	rank := int(math.Ceil(0.99 * float64(len(durations))))
+	return LatencyStats{
+		Pushes: len(durations),
+		Min:    durations[0],
+		Max:    durations[len(durations)-1],
+		P99:    durations[rank-1],
+	}
+}
+
+// recordLatency adds the duration of a push to the window, replacing the oldest one once
+// the window is full.
+func (e *Exporter) recordLatency(d time.Duration) {
+	window := e.config.LatencyWindow
+	if window <= 0 {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.latencies) < window {
+		e.latencies = append(e.latencies, d)
+		return
+	}
+	e.latencies[e.latencyNext] = d
+	e.latencyNext = (e.latencyNext + 1) % window
+}
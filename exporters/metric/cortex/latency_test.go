@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPushLatency checks whether the latency statistics cover only the last LatencyWindow
+// pushes.
+func TestPushLatency(t *testing.T) {
+	exporter := Exporter{config: Config{LatencyWindow: 3}}
+	require.Equal(t, LatencyStats{}, exporter.PushLatency())
+
+	tests := []struct {
+		testName string
+		latency  time.Duration
+		want     LatencyStats
+	}{
+		{
+			testName: "First push",
+			latency:  30 * time.Millisecond,
+			want:     LatencyStats{Pushes: 1, Min: 30 * time.Millisecond, Max: 30 * time.Millisecond, P99: 30 * time.Millisecond},
+		},
+		{
+			testName: "Faster push",
+			latency:  10 * time.Millisecond,
+			want:     LatencyStats{Pushes: 2, Min: 10 * time.Millisecond, Max: 30 * time.Millisecond, P99: 30 * time.Millisecond},
+		},
+		{
+			testName: "Slower push",
+			latency:  50 * time.Millisecond,
+			want:     LatencyStats{Pushes: 3, Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, P99: 50 * time.Millisecond},
+		},
+		{
+			testName: "First push leaves the window",
+			latency:  20 * time.Millisecond,
+			want:     LatencyStats{Pushes: 3, Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, P99: 50 * time.Millisecond},
+		},
+		{
+			testName: "Fastest push leaves the window",
+			latency:  40 * time.Millisecond,
+			want:     LatencyStats{Pushes: 3, Min: 20 * time.Millisecond, Max: 50 * time.Millisecond, P99: 50 * time.Millisecond},
+		},
+		{
+			testName: "Slowest push leaves the window",
+			latency:  25 * time.Millisecond,
+			want:     LatencyStats{Pushes: 3, Min: 20 * time.Millisecond, Max: 40 * time.Millisecond, P99: 40 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter.recordLatency(test.latency)
+			require.Equal(t, test.want, exporter.PushLatency())
+		})
+	}
+}
+
+// TestPushLatencyPercentile checks whether the 99th percentile ignores the slowest push
+// once the window holds more than 100 pushes.
+func TestPushLatencyPercentile(t *testing.T) {
+	exporter := Exporter{config: Config{LatencyWindow: 200}}
+	for i := 1; i <= 200; i++ {
+		exporter.recordLatency(time.Duration(i) * time.Millisecond)
+	}
+
+	stats := exporter.PushLatency()
+	require.Equal(t, 200, stats.Pushes)
+	require.Equal(t, time.Millisecond, stats.Min)
+	require.Equal(t, 200*time.Millisecond, stats.Max)
+	require.Equal(t, 198*time.Millisecond, stats.P99)
+}
+
+// TestPushLatencyExport checks whether pushes record how long they took and whether
+// nothing is recorded when LatencyWindow is not set.
+func TestPushLatencyExport(t *testing.T) {
+	delays := []time.Duration{20 * time.Millisecond, 0, 60 * time.Millisecond}
+	requests := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(delays[requests])
+		requests++
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{config: Config{Endpoint: server.URL, LatencyWindow: 10}}
+	for range delays {
+		require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	}
+
+	stats := exporter.PushLatency()
+	require.Equal(t, 3, stats.Pushes)
+	require.True(t, stats.Min < 20*time.Millisecond)
+	require.True(t, stats.Max >= 60*time.Millisecond)
+	require.Equal(t, stats.Max, stats.P99)
+
+	exporter = Exporter{config: Config{Endpoint: server.URL}}
+	requests = 0
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Equal(t, LatencyStats{}, exporter.PushLatency())
+}
@@ -0,0 +1,169 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var (
+	// ErrSeriesTooLarge occurs when the encoded size of a series is larger than
+	// MaxSeriesBytes and ValidationFailureMode is "closed".
+	ErrSeriesTooLarge = fmt.Errorf("Series is larger than max_series_bytes")
+)
+
+const (
+	// LabelNameLengthPolicyTruncate shortens label names longer than MaxLabelNameLength.
+	LabelNameLengthPolicyTruncate = "truncate"
+
+	// LabelNameLengthPolicyDrop removes labels whose names are longer than
+	// MaxLabelNameLength.
+	LabelNameLengthPolicyDrop = "drop"
+)
+
+// limitSamplesPerSeries drops the oldest samples of every TimeSeries with more than
+// MaxSamplesPerSeries samples.
+func (e *Exporter) limitSamplesPerSeries(timeSeries []*prompb.TimeSeries) {
+	max := e.config.MaxSamplesPerSeries
+	for _, ts := range timeSeries {
+		if len(ts.Samples) <= max {
+			continue
+		}
+
+		sort.SliceStable(ts.Samples, func(i, j int) bool {
+			return ts.Samples[i].Timestamp < ts.Samples[j].Timestamp
+		})
+		dropped := len(ts.Samples) - max
+		ts.Samples = ts.Samples[dropped:]
+		e.addDroppedSamples(dropped, "max_samples_per_series")
+	}
+}
+
+// warnSeriesCount logs a warning and counts it when a push has more than
+// SeriesWarnThreshold series. The series are sent regardless, so the warning gives time to
+// reduce the cardinality before a hard limit like MaxTotalSeries drops series.
+func (e *Exporter) warnSeriesCount(timeSeries []*prompb.TimeSeries) {
+	if len(timeSeries) <= e.config.SeriesWarnThreshold {
+		return
+	}
+	e.logf("Push has %d series, which is more than the warning threshold of %d", len(timeSeries), e.config.SeriesWarnThreshold)
+	e.addSeriesWarning()
+}
+
+// limitTotalSeries drops the TimeSeries with the lowest priority when a push has more
+// than MaxTotalSeries series. Priorities are computed with SeriesPriority, and series
+// with equal priority are kept in the order they were converted in. Without a
+// SeriesPriority, the last series are dropped.
+func (e *Exporter) limitTotalSeries(timeSeries []*prompb.TimeSeries) []*prompb.TimeSeries {
+	max := e.config.MaxTotalSeries
+	if len(timeSeries) <= max {
+		return timeSeries
+	}
+
+	order := make([]int, len(timeSeries))
+	priorities := make([]int, len(timeSeries))
+	for i, ts := range timeSeries {
+		order[i] = i
+		if e.config.SeriesPriority != nil {
+			priorities[i] = e.config.SeriesPriority(ts)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return priorities[order[i]] > priorities[order[j]]
+	})
+	kept := make([]bool, len(timeSeries))
+	for _, index := range order[:max] {
+		kept[index] = true
+	}
+
+	res := make([]*prompb.TimeSeries, 0, max)
+	for i, ts := range timeSeries {
+		if !kept[i] {
+			e.addDroppedSeries("max_total_series")
+			continue
+		}
+		res = append(res, ts)
+	}
+	return res
+}
+
+// limitSeriesBytes drops TimeSeries whose encoded size is larger than MaxSeriesBytes, or
+// fails the push if ValidationFailureMode is "closed". Such series cannot be split and
+// would be rejected by Cortex in every push. Each one is logged with its metric name so
+// that it can be found.
+func (e *Exporter) limitSeriesBytes(timeSeries []*prompb.TimeSeries) ([]*prompb.TimeSeries, error) {
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		if size := ts.Size(); size > e.config.MaxSeriesBytes {
+			if e.failClosed() {
+				e.logf("Series of metric %s with %d labels and %d samples is %d bytes, more than max_series_bytes %d. The push fails.",
+					metricName(ts), len(ts.Labels), len(ts.Samples), size, e.config.MaxSeriesBytes)
+				return nil, ErrSeriesTooLarge
+			}
+			e.logf("Series of metric %s with %d labels and %d samples is %d bytes, more than max_series_bytes %d. It is dropped.",
+				metricName(ts), len(ts.Labels), len(ts.Samples), size, e.config.MaxSeriesBytes)
+			e.addDroppedSeries("max_series_bytes")
+			continue
+		}
+		res = append(res, ts)
+	}
+	return res, nil
+}
+
+// metricName returns the value of the __name__ label of a TimeSeries.
+func metricName(ts *prompb.TimeSeries) string {
+	for _, label := range ts.Labels {
+		if label.Name == "__name__" {
+			return label.Value
+		}
+	}
+	return ""
+}
+
+// limitLabelNameLength truncates or drops labels whose names are longer than
+// MaxLabelNameLength, depending on LabelNameLengthPolicy. The metric name label is never
+// changed. A truncated label is dropped if its new name is already taken by another
+// label of the series.
+func (e *Exporter) limitLabelNameLength(timeSeries []*prompb.TimeSeries) {
+	max := e.config.MaxLabelNameLength
+	for _, ts := range timeSeries {
+		names := make(map[string]bool, len(ts.Labels))
+		for _, label := range ts.Labels {
+			names[label.Name] = true
+		}
+
+		labels := ts.Labels[:0]
+		for _, label := range ts.Labels {
+			if len(label.Name) <= max || label.Name == "__name__" {
+				labels = append(labels, label)
+				continue
+			}
+
+			truncated := label.Name[:max]
+			if e.config.LabelNameLengthPolicy == LabelNameLengthPolicyDrop || names[truncated] {
+				e.addLongLabelName("dropped")
+				continue
+			}
+			names[truncated] = true
+			label.Name = truncated
+			labels = append(labels, label)
+			e.addLongLabelName("truncated")
+		}
+		ts.Labels = labels
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLimitSamplesPerSeries checks whether only the newest MaxSamplesPerSeries samples
+// of a series are kept and whether the dropped samples are counted.
+func TestLimitSamplesPerSeries(t *testing.T) {
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			MaxSamplesPerSeries: 2,
+			MeterProvider:       controller.Provider(),
+		},
+	}
+
+	timeSeries := []*prompb.TimeSeries{
+		{
+			Labels: []*prompb.Label{{Name: "__name__", Value: "many"}},
+			Samples: []prompb.Sample{
+				{Value: 3, Timestamp: 3000},
+				{Value: 1, Timestamp: 1000},
+				{Value: 5, Timestamp: 5000},
+				{Value: 2, Timestamp: 2000},
+				{Value: 4, Timestamp: 4000},
+			},
+		},
+		{
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "one"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		},
+	}
+	exporter.limitSamplesPerSeries(timeSeries)
+
+	require.Equal(t, []prompb.Sample{
+		{Value: 4, Timestamp: 4000},
+		{Value: 5, Timestamp: 5000},
+	}, timeSeries[0].Samples)
+	require.Equal(t, []prompb.Sample{{Value: 1, Timestamp: 1000}}, timeSeries[1].Samples)
+
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_dropped_samples_total{reason=max_samples_per_series}": 3,
+	}, selfMetricValues(t, controller))
+}
+
+// TestLimitLabelNameLength checks whether labels with overlong names are truncated or
+// dropped depending on the policy, and whether each action is counted.
+func TestLimitLabelNameLength(t *testing.T) {
+	tests := []struct {
+		testName     string
+		policy       string
+		wantLabels   []*prompb.Label
+		wantCounters map[string]float64
+	}{
+		{
+			testName: "Truncate",
+			policy:   LabelNameLengthPolicyTruncate,
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "short", Value: "1"},
+				{Name: "very_", Value: "2"},
+			},
+			wantCounters: map[string]float64{
+				"cortex_exporter_long_label_names_total{action=dropped}":   1,
+				"cortex_exporter_long_label_names_total{action=truncated}": 1,
+			},
+		},
+		{
+			testName: "Drop",
+			policy:   LabelNameLengthPolicyDrop,
+			wantLabels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "short", Value: "1"},
+			},
+			wantCounters: map[string]float64{
+				"cortex_exporter_long_label_names_total{action=dropped}": 2,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					MaxLabelNameLength:    5,
+					LabelNameLengthPolicy: test.policy,
+					MeterProvider:         controller.Provider(),
+				},
+			}
+
+			// The second long label would have the same name as the first one after
+			// truncation, so it is dropped under both policies.
+			timeSeries := []*prompb.TimeSeries{
+				{
+					Labels: []*prompb.Label{
+						{Name: "__name__", Value: "metric_name"},
+						{Name: "short", Value: "1"},
+						{Name: "very_long_name", Value: "2"},
+						{Name: "very_long_name_too", Value: "3"},
+					},
+				},
+			}
+			exporter.limitLabelNameLength(timeSeries)
+
+			require.Equal(t, test.wantLabels, timeSeries[0].Labels)
+			require.Equal(t, test.wantCounters, selfMetricValues(t, controller))
+		})
+	}
+}
+
+// TestLimitTotalSeries checks whether the series with the lowest priority are dropped
+// when a push has more than MaxTotalSeries series, and whether they are counted.
+func TestLimitTotalSeries(t *testing.T) {
+	tests := []struct {
+		testName  string
+		priority  func(*prompb.TimeSeries) int
+		wantNames []string
+	}{
+		{
+			testName:  "Without priority",
+			wantNames: []string{"metric_0", "metric_1", "metric_2"},
+		},
+		{
+			testName: "With priority",
+			priority: func(ts *prompb.TimeSeries) int {
+				// Series with an odd value have a higher priority.
+				return int(ts.Samples[0].Value) % 2
+			},
+			wantNames: []string{"metric_0", "metric_1", "metric_3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					MaxTotalSeries: 3,
+					SeriesPriority: test.priority,
+					MeterProvider:  controller.Provider(),
+				},
+			}
+
+			got := exporter.limitTotalSeries(makeTimeSeries(5))
+			names := make([]string, len(got))
+			for i, ts := range got {
+				names[i] = ts.Labels[0].Value
+			}
+			require.Equal(t, test.wantNames, names)
+			require.Equal(t, map[string]float64{
+				"cortex_exporter_dropped_series_total{reason=max_total_series}": 2,
+			}, selfMetricValues(t, controller))
+		})
+	}
+}
+
+// TestLimitSeriesBytes checks whether series larger than MaxSeriesBytes are dropped,
+// logged, and counted while the other series are kept.
+func TestLimitSeriesBytes(t *testing.T) {
+	var logs bytes.Buffer
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			MaxSeriesBytes: 100,
+			Logger:         log.New(&logs, "", 0),
+			MeterProvider:  controller.Provider(),
+		},
+	}
+
+	timeSeries := []*prompb.TimeSeries{
+		{
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "small"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		},
+		{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: "large"},
+				{Name: "query", Value: strings.Repeat("x", 200)},
+			},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		},
+	}
+	got, err := exporter.limitSeriesBytes(timeSeries)
+	require.Nil(t, err)
+
+	require.Len(t, got, 1)
+	require.Equal(t, "small", metricName(got[0]))
+	require.Contains(t, logs.String(), "Series of metric large")
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_dropped_series_total{reason=max_series_bytes}": 1,
+	}, selfMetricValues(t, controller))
+}
+
+// TestWarnSeriesCount checks whether a warning is logged and counted only for pushes with
+// more series than SeriesWarnThreshold.
+func TestWarnSeriesCount(t *testing.T) {
+	var logs bytes.Buffer
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			SeriesWarnThreshold: 3,
+			Logger:              log.New(&logs, "", 0),
+			MeterProvider:       controller.Provider(),
+		},
+	}
+
+	exporter.warnSeriesCount(makeTimeSeries(3))
+	require.Empty(t, logs.String())
+
+	exporter.warnSeriesCount(makeTimeSeries(4))
+	require.Equal(t, "Push has 4 series, which is more than the warning threshold of 3\n", logs.String())
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_series_warnings_total{}": 1,
+	}, selfMetricValues(t, controller))
+}
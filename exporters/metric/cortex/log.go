@@ -0,0 +1,52 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/api/label"
+	"go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// logf logs a message with the Logger in the Config, or prints it to standard output if
+// no Logger is set. Messages are prefixed with the Name in the Config so that the logs
+// of several Exporters in one process can be told apart.
+func (e *Exporter) logf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if e.config.Name != "" {
+		message = e.config.Name + ": " + message
+	}
+
+	if e.config.Logger != nil {
+		e.config.Logger.Print(message)
+		return
+	}
+	fmt.Println(message)
+}
+
+// warnReservedLabels logs the labels of a record that are overwritten by the labels the
+// conversion adds, like "le" for histogram buckets.
+func (e *Exporter) warnReservedLabels(record metric.Record, reserved ...string) {
+	mi := label.NewMergeIterator(record.Labels(), record.Resource().LabelSet())
+	for mi.Next() {
+		name := sanitize(string(mi.Label().Key))
+		for _, reservedName := range reserved {
+			if name == reservedName {
+				e.logf("Label %s is overwritten. Check if Prometheus reserved labels are used.", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/metric"
+)
+
+// TestExporterName checks whether the Name in the Config is added to log lines and
+// self-metrics so that several Exporters can be told apart.
+func TestExporterName(t *testing.T) {
+	var logs bytes.Buffer
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Name:          "primary",
+			Logger:        log.New(&logs, "", 0),
+			MeterProvider: controller.Provider(),
+		},
+	}
+
+	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{}, kv.String("le", "1"))
+	exporter.warnReservedLabels(record, "le")
+	require.Equal(t, "primary: Label le is overwritten. Check if Prometheus reserved labels are used.\n", logs.String())
+
+	exporter.dropEmptySeries([]*prompb.TimeSeries{
+		{Labels: []*prompb.Label{{Name: "__name__", Value: "without_samples"}}},
+	})
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_dropped_series_total{exporter=primary,reason=no_samples}": 1,
+	}, selfMetricValues(t, controller))
+}
+
+// TestLogfWithoutName checks whether messages are logged without a prefix if the Name in
+// the Config is not set.
+func TestLogfWithoutName(t *testing.T) {
+	var logs bytes.Buffer
+	exporter := Exporter{config: Config{Logger: log.New(&logs, "", 0)}}
+
+	exporter.logf("No conversion found for record: %s", "metric_name")
+	require.Equal(t, "No conversion found for record: metric_name\n", logs.String())
+}
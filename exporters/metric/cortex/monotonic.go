@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// TimestampMonotonicPolicyError fails a push that contains a series whose sample
+	// timestamps are not strictly increasing.
+	TimestampMonotonicPolicyError = "error"
+
+	// TimestampMonotonicPolicyDrop drops samples whose timestamp is not after the
+	// timestamp of the previous sample of the same series.
+	TimestampMonotonicPolicyDrop = "drop"
+)
+
+var (
+	// ErrNonMonotonicTimestamps occurs when the samples of a series are not in strictly
+	// increasing timestamp order and TimestampMonotonicPolicy is "error".
+	ErrNonMonotonicTimestamps = fmt.Errorf("Sample timestamps within a series are not strictly increasing")
+)
+
+// checkMonotonicTimestamps checks whether the samples of every TimeSeries have strictly
+// increasing timestamps and handles violations according to TimestampMonotonicPolicy.
+func (e *Exporter) checkMonotonicTimestamps(timeSeries []*prompb.TimeSeries) error {
+	for _, ts := range timeSeries {
+		samples := ts.Samples[:0]
+		for _, sample := range ts.Samples {
+			if len(samples) > 0 && sample.Timestamp <= samples[len(samples)-1].Timestamp {
+				if e.config.TimestampMonotonicPolicy == TimestampMonotonicPolicyError {
+					return ErrNonMonotonicTimestamps
+				}
+				e.addDroppedSamples(1, "non_monotonic_timestamp")
+				continue
+			}
+			samples = append(samples, sample)
+		}
+		ts.Samples = samples
+	}
+	return nil
+}
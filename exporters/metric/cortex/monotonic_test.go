@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckMonotonicTimestamps checks whether samples with decreasing or repeated
+// timestamps fail the push or are dropped depending on TimestampMonotonicPolicy.
+func TestCheckMonotonicTimestamps(t *testing.T) {
+	tests := []struct {
+		testName      string
+		policy        string
+		wantSamples   []prompb.Sample
+		wantCounters  map[string]float64
+		expectedError error
+	}{
+		{
+			testName:      "Error",
+			policy:        TimestampMonotonicPolicyError,
+			expectedError: ErrNonMonotonicTimestamps,
+		},
+		{
+			testName: "Drop",
+			policy:   TimestampMonotonicPolicyDrop,
+			wantSamples: []prompb.Sample{
+				{Value: 1, Timestamp: 1000},
+				{Value: 3, Timestamp: 3000},
+				{Value: 5, Timestamp: 4000},
+			},
+			wantCounters: map[string]float64{
+				"cortex_exporter_dropped_samples_total{reason=non_monotonic_timestamp}": 2,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{
+				config: Config{
+					TimestampMonotonicPolicy: test.policy,
+					MeterProvider:            controller.Provider(),
+				},
+			}
+
+			timeSeries := []*prompb.TimeSeries{
+				{
+					Labels: []*prompb.Label{{Name: "__name__", Value: "metric_name"}},
+					Samples: []prompb.Sample{
+						{Value: 1, Timestamp: 1000},
+						{Value: 3, Timestamp: 3000},
+						{Value: 2, Timestamp: 2000},
+						{Value: 4, Timestamp: 3000},
+						{Value: 5, Timestamp: 4000},
+					},
+				},
+			}
+			err := exporter.checkMonotonicTimestamps(timeSeries)
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+
+			require.Nil(t, err)
+			require.Equal(t, test.wantSamples, timeSeries[0].Samples)
+			require.Equal(t, test.wantCounters, selfMetricValues(t, controller))
+		})
+	}
+}
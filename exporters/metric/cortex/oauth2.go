@@ -0,0 +1,140 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultAuthCacheSkew is how long before it expires an OAuth2 token is refreshed when
+// AuthCacheSkew is not set, so that requests in flight do not carry a token that expires
+// before the server checks it.
+const defaultAuthCacheSkew = 10 * time.Second
+
+var (
+	// ErrInvalidOAuth2Config occurs when the OAuth2 map does not contain a client_id,
+	// client_secret, and token_url.
+	ErrInvalidOAuth2Config = fmt.Errorf("OAuth2 requires a client_id, client_secret, and token_url")
+
+	// ErrFailedToFetchOAuth2Token occurs when no access token could be fetched from the
+	// OAuth2 token endpoint.
+	ErrFailedToFetchOAuth2Token = fmt.Errorf("Failed to fetch OAuth2 token")
+)
+
+// oauth2TokenResponse is the response of an OAuth2 token endpoint.
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// addOAuth2Auth sets the Authorization header to a bearer token fetched from the OAuth2
+// token endpoint with the client credentials grant. The token is cached and reused until
+// shortly before it expires.
+func (e *Exporter) addOAuth2Auth(req *http.Request) error {
+	// No need to add OAuth2 auth if it isn't provided or if the Authorization header is
+	// already set.
+	if _, exists := e.config.Headers["Authorization"]; exists {
+		return nil
+	}
+	if e.config.OAuth2 == nil {
+		return nil
+	}
+
+	token, err := e.oauth2Token(req.Context(), time.Now())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// authCacheSkew returns how long before it expires a cached token is refreshed.
+func (e *Exporter) authCacheSkew() time.Duration {
+	if e.config.AuthCacheSkew > 0 {
+		return e.config.AuthCacheSkew
+	}
+	return defaultAuthCacheSkew
+}
+
+// oauth2Token returns the cached OAuth2 access token, or fetches a new one if there is
+// none or it expires within AuthCacheSkew. Requests are sent concurrently, so the token
+// is fetched under a lock that makes concurrent requests wait for a single fetch.
+func (e *Exporter) oauth2Token(ctx context.Context, now time.Time) (string, error) {
+	e.oauth2Lock.Lock()
+	defer e.oauth2Lock.Unlock()
+
+	if e.oauth2AccessToken != "" && (e.oauth2Expiry.IsZero() || now.Add(e.authCacheSkew()).Before(e.oauth2Expiry)) {
+		return e.oauth2AccessToken, nil
+	}
+
+	response, err := e.fetchOAuth2Token(ctx)
+	if err != nil {
+		e.logf("Could not fetch OAuth2 token from %s: %v", e.config.OAuth2["token_url"], err)
+		return "", ErrFailedToFetchOAuth2Token
+	}
+
+	e.oauth2AccessToken = response.AccessToken
+	e.oauth2Expiry = time.Time{}
+	if response.ExpiresIn > 0 {
+		e.oauth2Expiry = now.Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return e.oauth2AccessToken, nil
+}
+
+// fetchOAuth2Token requests an access token from the OAuth2 token endpoint using the
+// client credentials grant. The client credentials are sent with basic authentication and
+// the optional scopes are separated by spaces.
+func (e *Exporter) fetchOAuth2Token(ctx context.Context) (*oauth2TokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if scopes := strings.Fields(e.config.OAuth2["scopes"]); len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.OAuth2["token_url"], strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(e.config.OAuth2["client_id"]), url.QueryEscape(e.config.OAuth2["client_secret"]))
+
+	client, err := e.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("token endpoint returned %s", res.Status)
+	}
+	var response oauth2TokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+	return &response, nil
+}
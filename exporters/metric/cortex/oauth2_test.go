@@ -0,0 +1,224 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newOAuth2Server returns a token endpoint that hands out numbered tokens which expire
+// after expiresIn seconds, and a function that returns how many tokens it handed out.
+func newOAuth2Server(t *testing.T, expiresIn int64) (*httptest.Server, func() int) {
+	var lock sync.Mutex
+	fetches := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		clientID, clientSecret, ok := req.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "client", clientID)
+		require.Equal(t, "secret", clientSecret)
+		require.Nil(t, req.ParseForm())
+		require.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+		require.Equal(t, "read write", req.PostForm.Get("scope"))
+
+		lock.Lock()
+		fetches++
+		token := string(rune('a' + fetches - 1))
+		lock.Unlock()
+
+		rw.Header().Set("Content-Type", "application/json")
+		require.Nil(t, json.NewEncoder(rw).Encode(oauth2TokenResponse{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn,
+		}))
+	}
+	count := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return fetches
+	}
+	return httptest.NewServer(http.HandlerFunc(handler)), count
+}
+
+// newOAuth2Exporter returns an Exporter that fetches OAuth2 tokens from the token URL.
+func newOAuth2Exporter(tokenURL string) *Exporter {
+	return &Exporter{
+		config: Config{
+			Client: http.DefaultClient,
+			OAuth2: map[string]string{
+				"client_id":     "client",
+				"client_secret": "secret",
+				"token_url":     tokenURL,
+				"scopes":        "read write",
+			},
+		},
+	}
+}
+
+// TestOAuth2Authentication checks whether addHeaders adds a bearer token fetched from the
+// token endpoint and reuses it for later requests.
+func TestOAuth2Authentication(t *testing.T) {
+	server, fetches := newOAuth2Server(t, 3600)
+	defer server.Close()
+	exporter := newOAuth2Exporter(server.URL)
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodPost, "http://cortex", nil)
+		require.Nil(t, err)
+		require.Nil(t, exporter.addHeaders(req))
+		require.Equal(t, "Bearer a", req.Header.Get("Authorization"))
+	}
+	require.Equal(t, 1, fetches())
+}
+
+// TestOAuth2TokenRefresh checks whether a token is refreshed shortly before it expires.
+func TestOAuth2TokenRefresh(t *testing.T) {
+	server, fetches := newOAuth2Server(t, 60)
+	defer server.Close()
+	exporter := newOAuth2Exporter(server.URL)
+	now := time.Now()
+
+	tests := []struct {
+		testName  string
+		elapsed   time.Duration
+		wantToken string
+	}{
+		{"First token is fetched", 0, "a"},
+		{"Token is reused before it expires", 30 * time.Second, "a"},
+		{"Token is refreshed shortly before it expires", 55 * time.Second, "b"},
+		{"Refreshed token is reused", 60 * time.Second, "b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			token, err := exporter.oauth2Token(context.Background(), now.Add(test.elapsed))
+			require.Nil(t, err)
+			require.Equal(t, test.wantToken, token)
+		})
+	}
+	require.Equal(t, 2, fetches())
+}
+
+// TestAuthCacheSkew checks whether a cached token is reused until AuthCacheSkew before it
+// expires.
+func TestAuthCacheSkew(t *testing.T) {
+	server, fetches := newOAuth2Server(t, 60)
+	defer server.Close()
+	exporter := newOAuth2Exporter(server.URL)
+	exporter.config.AuthCacheSkew = 30 * time.Second
+	now := time.Now()
+
+	tests := []struct {
+		testName  string
+		elapsed   time.Duration
+		wantToken string
+	}{
+		{"First token is fetched", 0, "a"},
+		{"Token is reused before the skew window", 29 * time.Second, "a"},
+		{"Token is refreshed within the skew window", 31 * time.Second, "b"},
+		{"Refreshed token is reused", 45 * time.Second, "b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			token, err := exporter.oauth2Token(context.Background(), now.Add(test.elapsed))
+			require.Nil(t, err)
+			require.Equal(t, test.wantToken, token)
+		})
+	}
+	require.Equal(t, 2, fetches())
+}
+
+// TestOAuth2ConcurrentRequests checks whether concurrent requests share a single token
+// fetch.
+func TestOAuth2ConcurrentRequests(t *testing.T) {
+	server, fetches := newOAuth2Server(t, 3600)
+	defer server.Close()
+	exporter := newOAuth2Exporter(server.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodPost, "http://cortex", nil)
+			require.Nil(t, err)
+			require.Nil(t, exporter.addHeaders(req))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, fetches())
+}
+
+// TestOAuth2FetchError checks whether an unreachable or failing token endpoint results in
+// ErrFailedToFetchOAuth2Token.
+func TestOAuth2FetchError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	for _, tokenURL := range []string{failing.URL, unreachable.URL} {
+		req, err := http.NewRequest(http.MethodPost, "http://cortex", nil)
+		require.Nil(t, err)
+		err = newOAuth2Exporter(tokenURL).addHeaders(req)
+		require.Equal(t, ErrFailedToFetchOAuth2Token, err)
+	}
+}
+
+// TestOAuth2ExportError checks whether a push fails with ErrFailedToFetchOAuth2Token
+// without sending anything when no token can be fetched, and whether fetching the token
+// is bounded by the context of the push.
+func TestOAuth2ExportError(t *testing.T) {
+	pushes := 0
+	cortex := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		pushes++
+	}))
+	defer cortex.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	done := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-done
+	}))
+	defer hanging.Close()
+	defer close(done)
+
+	for _, tokenURL := range []string{failing.URL, hanging.URL} {
+		exporter := newOAuth2Exporter(tokenURL)
+		exporter.config.Endpoint = cortex.URL
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		err := exporter.Export(ctx, getSumCheckpoint(t, 1))
+		cancel()
+		require.Equal(t, ErrFailedToFetchOAuth2Token, err)
+		require.True(t, time.Since(start) < 5*time.Second)
+	}
+	require.Equal(t, 0, pushes)
+}
@@ -0,0 +1,38 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+// Pause stops the Exporter from sending metrics, for example during a maintenance window
+// of Cortex, without shutting down the pipeline. Pushes while the Exporter is paused
+// succeed without sending anything, so their metrics are dropped.
+func (e *Exporter) Pause() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.paused = true
+}
+
+// Resume makes a paused Exporter send metrics again, starting with the next push.
+func (e *Exporter) Resume() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.paused = false
+}
+
+// Paused reports whether the Exporter is paused.
+func (e *Exporter) Paused() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.paused
+}
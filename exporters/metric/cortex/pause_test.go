@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPause checks whether no requests are sent while the Exporter is paused and whether
+// pushes are sent again after it is resumed.
+func TestPause(t *testing.T) {
+	requests := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			MeterProvider: controller.Provider(),
+		},
+	}
+
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Equal(t, 1, requests)
+
+	exporter.Pause()
+	require.True(t, exporter.Paused())
+	for i := 0; i < 3; i++ {
+		result, err := exporter.ExportWithResult(context.Background(), getSumCheckpoint(t, 1))
+		require.Nil(t, err)
+		require.Equal(t, ExportResult{}, result)
+	}
+	require.Equal(t, 1, requests)
+	require.Equal(t, float64(3), selfMetricValues(t, controller)["cortex_exporter_paused_pushes_total{}"])
+
+	exporter.Resume()
+	require.False(t, exporter.Paused())
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Equal(t, 2, requests)
+}
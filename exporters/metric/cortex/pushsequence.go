@@ -0,0 +1,41 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// pushSequenceName is the name of the series pushed when EmitPushSequence is set.
+const pushSequenceName = "otel_cortex_exporter_push_sequence"
+
+// pushSequenceTimeSeries returns a TimeSeries whose value is the sequence number of the
+// push, which starts at 1 and increments with every push. A gap or repetition in the
+// values shows that pushes were dropped or duplicated on the way to Cortex. The number is
+// the value rather than a label so that it does not create a new series for every push.
+func (e *Exporter) pushSequenceTimeSeries(now time.Time) *prompb.TimeSeries {
+	return &prompb.TimeSeries{
+		Labels: []*prompb.Label{
+			{Name: "__name__", Value: pushSequenceName},
+		},
+		Samples: []prompb.Sample{{
+			Value:     float64(atomic.AddUint64(&e.pushSequence, 1)),
+			Timestamp: now.UnixNano() / int64(time.Millisecond),
+		}},
+	}
+}
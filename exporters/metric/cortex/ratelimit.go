@@ -0,0 +1,109 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tenantHeader is the header Cortex reads the tenant ID of a request from.
+const tenantHeader = "X-Scope-OrgID"
+
+// tokenBucket limits the rate of requests. It holds at most one token, so requests are
+// spread evenly instead of being sent in bursts.
+type tokenBucket struct {
+	lock   sync.Mutex
+	clock  waitClock
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full tokenBucket that allows rate requests per second.
+func newTokenBucket(rate float64, clock waitClock) *tokenBucket {
+	return &tokenBucket{clock: clock, rate: rate, tokens: 1, last: clock.Now()}
+}
+
+// wait takes a token, waiting until one is available or the context is done. Waiting
+// requests reserve their token up front, so they are served in order. The token is
+// given back if the context is done first, so that a canceled request does not delay the
+// ones after it.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.lock.Lock()
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+	b.last = now
+	b.tokens--
+	tokens := b.tokens
+	b.lock.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+	if err := b.clock.Sleep(ctx, time.Duration(-tokens/b.rate*float64(time.Second))); err != nil {
+		b.lock.Lock()
+		b.tokens++
+		b.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// requestTenant returns the tenant requests are sent for, which is the tenant of the
+// context if it has one and the X-Scope-OrgID header of Config.Headers otherwise.
+func (e *Exporter) requestTenant(ctx context.Context) string {
+	if tenant, ok := tenantFromContext(ctx); ok {
+		return tenant
+	}
+	for name, value := range e.config.Headers {
+		if http.CanonicalHeaderKey(name) == tenantHeader {
+			return value
+		}
+	}
+	return ""
+}
+
+// waitForTenant waits until a request for a tenant may be sent according to
+// PerTenantRateLimits. The tenant of a request is its X-Scope-OrgID header. Requests of
+// tenants without a rate limit are not delayed.
+func (e *Exporter) waitForTenant(ctx context.Context, tenant string) error {
+	rate, ok := e.config.PerTenantRateLimits[tenant]
+	if !ok {
+		return nil
+	}
+
+	e.lock.Lock()
+	if e.tenantLimiters == nil {
+		e.tenantLimiters = make(map[string]*tokenBucket)
+	}
+	limiter, found := e.tenantLimiters[tenant]
+	if !found {
+		clock := e.rateLimitClock
+		if clock == nil {
+			clock = realWaitClock{}
+		}
+		limiter = newTokenBucket(rate, clock)
+		e.tenantLimiters[tenant] = limiter
+	}
+	e.lock.Unlock()
+
+	return limiter.wait(ctx)
+}
@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWaitClock is a waitClock whose time only advances when it sleeps, and that records
+// how long it slept.
+type fakeWaitClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// Now returns the fake time.
+func (c *fakeWaitClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Sleep records the duration and advances the fake time by it, unless the context is
+// already done.
+func (c *fakeWaitClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+// advance moves the fake time forward.
+func (c *fakeWaitClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestPerTenantRateLimits checks whether every tenant is limited by its own rate when
+// pushes are sent for several tenants, so that requests of one tenant do not use up the
+// budget of another.
+func TestPerTenantRateLimits(t *testing.T) {
+	var lock sync.Mutex
+	tenants := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		tenants[req.Header.Get(tenantHeader)]++
+	}))
+	defer server.Close()
+
+	clock := &fakeWaitClock{now: time.Unix(0, 0)}
+	exporter := Exporter{
+		config: Config{
+			Endpoint: server.URL,
+			PerTenantRateLimits: map[string]float64{
+				"team-a": 10,
+				"team-b": 10,
+			},
+		},
+		rateLimitClock: clock,
+	}
+
+	// Each tenant pushes 3 times at 10 per second. Only the time one tenant waits for
+	// its own tokens passes, which the other tenant's tokens refill in.
+	teamA := withTenant(context.Background(), "team-a")
+	teamB := withTenant(context.Background(), "team-b")
+	for i := 0; i < 3; i++ {
+		require.Nil(t, exporter.Export(teamA, getSumCheckpoint(t, 1)))
+		require.Nil(t, exporter.Export(teamB, getSumCheckpoint(t, 1)))
+	}
+	require.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, clock.sleeps)
+
+	// Tenants without a limit are not delayed.
+	teamC := withTenant(context.Background(), "team-c")
+	for i := 0; i < 3; i++ {
+		require.Nil(t, exporter.Export(teamC, getSumCheckpoint(t, 1)))
+	}
+	require.Len(t, clock.sleeps, 2)
+	require.Equal(t, map[string]int{"team-a": 3, "team-b": 3, "team-c": 3}, tenants)
+}
+
+// TestTenantRateLimitCanceled checks whether waiting for a token stops when the context
+// is done and whether the token is given back.
+func TestTenantRateLimitCanceled(t *testing.T) {
+	clock := &fakeWaitClock{now: time.Unix(0, 0)}
+	exporter := Exporter{
+		config: Config{
+			PerTenantRateLimits: map[string]float64{"team-a": 0.1},
+		},
+		rateLimitClock: clock,
+	}
+	require.NoError(t, exporter.waitForTenant(context.Background(), "team-a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, exporter.waitForTenant(ctx, "team-a"))
+
+	// The next token is available 10s after the first one, as if the canceled request
+	// was never made.
+	clock.advance(10 * time.Second)
+	require.NoError(t, exporter.waitForTenant(context.Background(), "team-a"))
+	require.Empty(t, clock.sleeps)
+}
@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// RelabelActionReplace sets TargetLabel to Replacement if Regex matches the
+	// concatenated SourceLabels.
+	RelabelActionReplace = "replace"
+
+	// RelabelActionKeep drops series for which Regex does not match the concatenated
+	// SourceLabels.
+	RelabelActionKeep = "keep"
+
+	// RelabelActionDrop drops series for which Regex matches the concatenated
+	// SourceLabels.
+	RelabelActionDrop = "drop"
+
+	// RelabelActionLabelMap copies the values of all labels whose name matches Regex to
+	// labels named by Replacement.
+	RelabelActionLabelMap = "labelmap"
+)
+
+// RelabelConfig configures a relabeling step. It supports a subset of the actions of
+// Prometheus' relabel_configs, and its properties have the same meaning and defaults.
+type RelabelConfig struct {
+	SourceLabels []string `mapstructure:"source_labels"`
+	Separator    string   `mapstructure:"separator"`
+	Regex        string   `mapstructure:"regex"`
+	TargetLabel  string   `mapstructure:"target_label"`
+	Replacement  string   `mapstructure:"replacement"`
+	Action       string   `mapstructure:"action"`
+}
+
+// setDefaults adds default values to missing properties of a RelabelConfig.
+func (r *RelabelConfig) setDefaults() {
+	if r.Separator == "" {
+		r.Separator = ";"
+	}
+	if r.Regex == "" {
+		r.Regex = "(.*)"
+	}
+	if r.Replacement == "" {
+		r.Replacement = "$1"
+	}
+	if r.Action == "" {
+		r.Action = RelabelActionReplace
+	}
+}
+
+// valid returns whether a RelabelConfig with defaults has a supported action, a regular
+// expression that compiles, and a target label if the action needs one.
+func (r *RelabelConfig) valid() bool {
+	switch r.Action {
+	case RelabelActionReplace:
+		if r.TargetLabel == "" {
+			return false
+		}
+	case RelabelActionKeep, RelabelActionDrop, RelabelActionLabelMap:
+	default:
+		return false
+	}
+	_, err := regexp.Compile(anchorRelabelRegex(r.Regex))
+	return err == nil
+}
+
+// anchorRelabelRegex anchors a relabeling regular expression so that, like in
+// Prometheus, it has to match the whole value.
+func anchorRelabelRegex(regex string) string {
+	return "^(?:" + regex + ")$"
+}
+
+// relabelRegexps returns the compiled regular expressions of the RelabelConfigs. They are
+// compiled the first time, and Validate ensures that they compile.
+func (e *Exporter) relabelRegexps() []*regexp.Regexp {
+	e.relabelOnce.Do(func() {
+		e.relabelRegexs = make([]*regexp.Regexp, len(e.config.RelabelConfigs))
+		for i, config := range e.config.RelabelConfigs {
+			e.relabelRegexs[i] = regexp.MustCompile(anchorRelabelRegex(config.Regex))
+		}
+	})
+	return e.relabelRegexs
+}
+
+// relabel applies the RelabelConfigs to the labels of a series in order. It returns nil
+// if the series is dropped. Labels left with an empty value are removed.
+func (e *Exporter) relabel(labels []*prompb.Label) []*prompb.Label {
+	values := make(map[string]string, len(labels))
+	for _, l := range labels {
+		values[l.Name] = l.Value
+	}
+
+	regexps := e.relabelRegexps()
+	for i, config := range e.config.RelabelConfigs {
+		regex := regexps[i]
+
+		sourceValues := make([]string, len(config.SourceLabels))
+		for j, name := range config.SourceLabels {
+			sourceValues[j] = values[name]
+		}
+		source := strings.Join(sourceValues, config.Separator)
+
+		switch config.Action {
+		case RelabelActionKeep:
+			if !regex.MatchString(source) {
+				return nil
+			}
+		case RelabelActionDrop:
+			if regex.MatchString(source) {
+				return nil
+			}
+		case RelabelActionReplace:
+			indexes := regex.FindStringSubmatchIndex(source)
+			if indexes == nil {
+				continue
+			}
+			target := string(regex.ExpandString(nil, config.TargetLabel, source, indexes))
+			values[target] = string(regex.ExpandString(nil, config.Replacement, source, indexes))
+		case RelabelActionLabelMap:
+			mapped := map[string]string{}
+			for name, value := range values {
+				if regex.MatchString(name) {
+					mapped[regex.ReplaceAllString(name, config.Replacement)] = value
+				}
+			}
+			for name, value := range mapped {
+				values[name] = value
+			}
+		}
+	}
+
+	res := make([]*prompb.Label, 0, len(values))
+	for name, value := range values {
+		if value != "" {
+			res = append(res, &prompb.Label{Name: name, Value: value})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
@@ -0,0 +1,195 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// relabelSeries returns the series the relabeling tests are run against.
+func relabelSeries() []*prompb.TimeSeries {
+	series := func(name, job string) *prompb.TimeSeries {
+		return &prompb.TimeSeries{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: name},
+				{Name: "job", Value: job},
+				{Name: "instance", Value: "host:9090"},
+				{Name: "env_region", Value: "east"},
+			},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		}
+	}
+	return []*prompb.TimeSeries{
+		series("http_requests", "api"),
+		series("queue_length", "worker"),
+	}
+}
+
+// TestRelabel checks whether each supported relabel action is applied to a set of series.
+func TestRelabel(t *testing.T) {
+	tests := []struct {
+		testName string
+		configs  []RelabelConfig
+		want     [][]*prompb.Label
+	}{
+		{
+			testName: "Keep",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"job"}, Regex: "api", Action: RelabelActionKeep},
+			},
+			want: [][]*prompb.Label{
+				{
+					{Name: "__name__", Value: "http_requests"},
+					{Name: "env_region", Value: "east"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "api"},
+				},
+			},
+		},
+		{
+			testName: "Drop",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"__name__"}, Regex: "http_.*", Action: RelabelActionDrop},
+			},
+			want: [][]*prompb.Label{
+				{
+					{Name: "__name__", Value: "queue_length"},
+					{Name: "env_region", Value: "east"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "worker"},
+				},
+			},
+		},
+		{
+			testName: "Replace",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"job", "instance"}, Regex: "(.*);(.*):.*", TargetLabel: "host", Replacement: "$1@$2"},
+				{SourceLabels: []string{"env_region"}, Regex: "west", TargetLabel: "job", Replacement: "unused"},
+			},
+			want: [][]*prompb.Label{
+				{
+					{Name: "__name__", Value: "http_requests"},
+					{Name: "env_region", Value: "east"},
+					{Name: "host", Value: "api@host"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "api"},
+				},
+				{
+					{Name: "__name__", Value: "queue_length"},
+					{Name: "env_region", Value: "east"},
+					{Name: "host", Value: "worker@host"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "worker"},
+				},
+			},
+		},
+		{
+			testName: "Replace with empty value removes label",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"job"}, Regex: "worker", TargetLabel: "instance", Replacement: "$2"},
+			},
+			want: [][]*prompb.Label{
+				{
+					{Name: "__name__", Value: "http_requests"},
+					{Name: "env_region", Value: "east"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "api"},
+				},
+				{
+					{Name: "__name__", Value: "queue_length"},
+					{Name: "env_region", Value: "east"},
+					{Name: "job", Value: "worker"},
+				},
+			},
+		},
+		{
+			testName: "Labelmap",
+			configs: []RelabelConfig{
+				{Regex: "env_(.+)", Action: RelabelActionLabelMap},
+			},
+			want: [][]*prompb.Label{
+				{
+					{Name: "__name__", Value: "http_requests"},
+					{Name: "env_region", Value: "east"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "api"},
+					{Name: "region", Value: "east"},
+				},
+				{
+					{Name: "__name__", Value: "queue_length"},
+					{Name: "env_region", Value: "east"},
+					{Name: "instance", Value: "host:9090"},
+					{Name: "job", Value: "worker"},
+					{Name: "region", Value: "east"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			config := Config{RelabelConfigs: test.configs}
+			require.Nil(t, config.Validate())
+			exporter := Exporter{config: config}
+
+			got := exporter.relabelTimeSeries(relabelSeries())
+			require.Len(t, got, len(test.want))
+			for i, ts := range got {
+				require.Equal(t, test.want[i], ts.Labels)
+			}
+		})
+	}
+}
+
+// TestRelabelConfigValid checks whether relabel configs with an unsupported action, an
+// invalid regex, or a replace action without a target label are rejected.
+func TestRelabelConfigValid(t *testing.T) {
+	tests := []struct {
+		testName string
+		config   RelabelConfig
+		want     bool
+	}{
+		{
+			testName: "Valid replace",
+			config:   RelabelConfig{TargetLabel: "target"},
+			want:     true,
+		},
+		{
+			testName: "Replace without target label",
+			config:   RelabelConfig{},
+			want:     false,
+		},
+		{
+			testName: "Unsupported action",
+			config:   RelabelConfig{Action: "hashmod"},
+			want:     false,
+		},
+		{
+			testName: "Invalid regex",
+			config:   RelabelConfig{Regex: "(", Action: RelabelActionKeep},
+			want:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			test.config.setDefaults()
+			require.Equal(t, test.want, test.config.valid())
+		})
+	}
+}
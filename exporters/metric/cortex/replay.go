@@ -0,0 +1,59 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var (
+	// ErrInvalidReplayFile occurs when a file passed to ReplayFile does not contain a
+	// Snappy-compressed WriteRequest.
+	ErrInvalidReplayFile = fmt.Errorf("Replay file does not contain a compressed WriteRequest")
+)
+
+// ReplayFile reads a captured Snappy-compressed WriteRequest from a file and sends it to
+// the configured endpoint. The file is expected to hold the exact request body the
+// Exporter would have sent, which makes it possible to upload payloads captured in an
+// air-gapped environment or to replay the payload of an incident.
+func (e *Exporter) ReplayFile(ctx context.Context, path string) error {
+	message, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Check that the file holds a valid message before sending it to Cortex.
+	uncompressed, err := snappy.Decode(nil, message)
+	if err != nil {
+		return ErrInvalidReplayFile
+	}
+	if err := proto.Unmarshal(uncompressed, &prompb.WriteRequest{}); err != nil {
+		return ErrInvalidReplayFile
+	}
+
+	// The message is sent with the configured compression.
+	if e.contentEncoding() != "snappy" {
+		if message, err = e.compress(uncompressed); err != nil {
+			return err
+		}
+	}
+	return e.send(ctx, message, &ExportResult{})
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReplayFile checks whether a captured WriteRequest can be written to a file and then
+// replayed so that the server receives the same series.
+func TestReplayFile(t *testing.T) {
+	// Set up a test server that decodes and stores the WriteRequest it receives.
+	var received prompb.WriteRequest
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		compressed, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		uncompressed, err := snappy.Decode(nil, compressed)
+		require.Nil(t, err)
+		require.Nil(t, proto.Unmarshal(uncompressed, &received))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{config: Config{Endpoint: server.URL}}
+
+	// Capture a payload to a file the same way it would be sent.
+	timeseries := []*prompb.TimeSeries{
+		{
+			Labels: []*prompb.Label{
+				{Name: "__name__", Value: "metric_name"},
+				{Name: "R", Value: "V"},
+			},
+			Samples: []prompb.Sample{{Value: 321, Timestamp: 1000}},
+		},
+	}
+	message, err := exporter.buildMessage(timeseries)
+	require.Nil(t, err)
+	require.Nil(t, createFile(message, "./payload.snappy"))
+	defer os.Remove("./payload.snappy")
+
+	// Replay the payload and verify that the server received identical series.
+	require.Nil(t, exporter.ReplayFile(context.Background(), "./payload.snappy"))
+	require.Equal(t, timeseries, received.Timeseries)
+}
+
+// TestReplayFileInvalid checks whether ReplayFile refuses to send files that do not
+// contain a compressed WriteRequest.
+func TestReplayFileInvalid(t *testing.T) {
+	exporter := Exporter{config: Config{}}
+
+	require.Nil(t, createFile([]byte("not a payload"), "./payload.snappy"))
+	defer os.Remove("./payload.snappy")
+
+	err := exporter.ReplayFile(context.Background(), "./payload.snappy")
+	require.Equal(t, ErrInvalidReplayFile, err)
+}
@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// detectResourceChanges returns whether changes of the resource of the records are
+// detected.
+func (e *Exporter) detectResourceChanges() bool {
+	return e.config.DetectResourceChanges || e.config.MarkStaleOnResourceChange
+}
+
+// noteResource remembers the resource of a converted record so that checkResourceChange
+// can compare the resources of consecutive pushes.
+func (e *Exporter) noteResource(res *resource.Resource) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.pushResources == nil {
+		e.pushResources = make(map[string]bool)
+	}
+	e.pushResources[res.String()] = true
+}
+
+// checkResourceChange compares the resources of the records converted since the last
+// push with the ones of the last push. A change is logged and counted, since the labels
+// promoted from the resource change along with it and split every series in two.
+func (e *Exporter) checkResourceChange() bool {
+	e.lock.Lock()
+	current := joinResources(e.pushResources)
+	previous := e.lastResources
+	e.pushResources = nil
+	if current != "" {
+		e.lastResources = current
+	}
+	e.lock.Unlock()
+
+	if previous == "" || current == "" || current == previous {
+		return false
+	}
+	e.logf("Resource changed from %s to %s, which changes the labels of all series", previous, current)
+	e.addResourceChange()
+	return true
+}
+
+// joinResources returns the sorted resources of a push as a single string.
+func joinResources(resources map[string]bool) string {
+	sorted := make([]string, 0, len(resources))
+	for res := range resources {
+		sorted = append(sorted, "{"+res+"}")
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
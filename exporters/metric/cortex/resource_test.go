@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestResourceChange checks whether a change of the resource between pushes is logged and
+// counted, and whether the series of the old resource are marked stale.
+func TestResourceChange(t *testing.T) {
+	var received prompb.WriteRequest
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		decoded, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		received = *decoded
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Endpoint:                  server.URL,
+			MarkStaleOnResourceChange: true,
+			Logger:                    log.New(&logs, "", 0),
+			MeterProvider:             controller.Provider(),
+		},
+	}
+
+	desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+	push := func(res *resource.Resource) {
+		record := newSumRecord(t, &desc, 1, time.Time{}, time.Time{})
+		record = export.NewRecord(record.Descriptor(), record.Labels(), res, record.Aggregation(), record.StartTime(), record.EndTime())
+		require.Nil(t, exporter.Export(context.Background(), &recordCheckpointSet{records: []export.Record{record}}))
+	}
+
+	push(resource.New(kv.String("host", "a")))
+	push(resource.New(kv.String("host", "a")))
+	require.Empty(t, logs.String())
+	require.Len(t, received.Timeseries, 1)
+
+	push(resource.New(kv.String("host", "b")))
+	require.True(t, strings.HasPrefix(logs.String(), "Resource changed from {host=a} to {host=b}"), logs.String())
+	require.Equal(t, float64(1), selfMetricValues(t, controller)["cortex_exporter_resource_changes_total{}"])
+
+	// The series of the old resource is marked stale.
+	require.Len(t, received.Timeseries, 2)
+	var stale *prompb.TimeSeries
+	for _, ts := range received.Timeseries {
+		if value.IsStaleNaN(ts.Samples[0].Value) {
+			stale = ts
+		}
+	}
+	require.NotNil(t, stale)
+	require.Contains(t, stale.Labels, &prompb.Label{Name: "host", Value: "a"})
+}
@@ -0,0 +1,154 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Reasons for retrying a request, which are used as labels of the retry metrics.
+const (
+	retryReasonDialError       = "dial_error"
+	retryReasonTooManyRequests = "429"
+	retryReasonServerError     = "5xx"
+)
+
+// RetryConfig configures how many times and how often a failed request is retried. The
+// time between attempts starts at InitialInterval and is multiplied by Multiplier after
+// every attempt, up to MaxInterval. A random jitter of up to half the time is subtracted
+// so that Exporters that failed at the same time do not retry at the same time.
+type RetryConfig struct {
+	MaxRetries      int           `mapstructure:"max_retries"`
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval"`
+	Multiplier      float64       `mapstructure:"multiplier"`
+}
+
+// setDefaults adds default values to missing properties of a RetryConfig.
+func (r *RetryConfig) setDefaults() {
+	if r.MaxRetries == 0 {
+		r.MaxRetries = 3
+	}
+	if r.InitialInterval == 0 {
+		r.InitialInterval = 100 * time.Millisecond
+	}
+	if r.MaxInterval == 0 {
+		r.MaxInterval = 5 * time.Second
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = 2
+	}
+}
+
+// backoff returns how long to wait before retrying after the given number of retries.
+func (r *RetryConfig) backoff(retries int) time.Duration {
+	interval := float64(r.InitialInterval) * math.Pow(r.Multiplier, float64(retries))
+	if interval > float64(r.MaxInterval) {
+		return r.MaxInterval
+	}
+	return time.Duration(interval)
+}
+
+// jitter subtracts a random part of up to half of the duration. random returns a number
+// in [0, 1).
+func jitter(d time.Duration, random func() float64) time.Duration {
+	return d - time.Duration(random()*float64(d/2))
+}
+
+// isDialError reports whether an error occurred while connecting to the endpoint, which
+// means no part of the request reached the server.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryReason classifies why a request failed for the retry metrics. It returns an
+// empty string if the request succeeded or failed for a reason that is never retried.
+func retryReason(statusCode int, err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case isDialError(err):
+		return retryReasonDialError
+	case statusCode == http.StatusTooManyRequests:
+		return retryReasonTooManyRequests
+	case isRetryableStatus(statusCode):
+		return retryReasonServerError
+	}
+	return ""
+}
+
+// isRetryableStatus reports whether a response with the status code indicates a transient
+// failure that may succeed when the request is sent again.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryPolicy returns the RetryConfig that applies to a request that failed for the
+// reason, or nil if it is not retried. Dial errors are retried according to
+// RetryOnDialError if it is set and according to RetryConfig otherwise.
+func (e *Exporter) retryPolicy(reason string) *RetryConfig {
+	switch {
+	case reason == "":
+		return nil
+	case reason == retryReasonDialError && e.config.RetryOnDialError != nil:
+		return e.config.RetryOnDialError
+	}
+	return e.config.RetryConfig
+}
+
+// parseRetryAfter returns how long a Retry-After header value asks to wait, which is
+// either a number of seconds or an HTTP date. It returns 0 if the value is missing or
+// invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// sleep waits for the duration to pass or for the context to be done, whichever happens
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
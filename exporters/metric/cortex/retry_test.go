@@ -0,0 +1,387 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyDialClient returns a Client whose first dials fail, up to failures, before any
+// connection is made. The returned counter records the number of dials.
+func flakyDialClient(failures int) (*http.Client, *int) {
+	dials := 0
+	dialer := net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials++
+				if dials <= failures {
+					return nil, &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("connection refused")}
+				}
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	return client, &dials
+}
+
+// TestSendRetryOnDialError checks whether requests are retried only when the connection
+// to the endpoint could not be established.
+func TestSendRetryOnDialError(t *testing.T) {
+	tests := []struct {
+		testName      string
+		failures      int
+		statusCode    int
+		retry         *RetryConfig
+		wantDials     int
+		wantRequests  int
+		expectedError bool
+	}{
+		{
+			testName:     "Dial error is retried",
+			failures:     1,
+			statusCode:   http.StatusOK,
+			retry:        &RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond},
+			wantDials:    2,
+			wantRequests: 1,
+		},
+		{
+			testName:      "Dial error without RetryOnDialError",
+			failures:      1,
+			statusCode:    http.StatusOK,
+			retry:         nil,
+			wantDials:     1,
+			wantRequests:  0,
+			expectedError: true,
+		},
+		{
+			testName:      "Dial error after MaxRetries",
+			failures:      5,
+			statusCode:    http.StatusOK,
+			retry:         &RetryConfig{MaxRetries: 2, InitialInterval: time.Millisecond},
+			wantDials:     3,
+			wantRequests:  0,
+			expectedError: true,
+		},
+		{
+			testName:      "Server error is not retried",
+			failures:      0,
+			statusCode:    http.StatusInternalServerError,
+			retry:         &RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond},
+			wantDials:     1,
+			wantRequests:  1,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			requests := 0
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				requests++
+				rw.WriteHeader(test.statusCode)
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			if test.retry != nil {
+				test.retry.setDefaults()
+			}
+			client, dials := flakyDialClient(test.failures)
+			exporter := Exporter{
+				config: Config{
+					Endpoint:         server.URL,
+					RetryOnDialError: test.retry,
+					Client:           client,
+				},
+			}
+
+			err := exporter.send(context.Background(), []byte("message"), &ExportResult{})
+			if test.expectedError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.wantDials, *dials)
+			require.Equal(t, test.wantRequests, requests)
+		})
+	}
+}
+
+// TestBackoff checks whether the time between retries grows by Multiplier up to
+// MaxInterval.
+func TestBackoff(t *testing.T) {
+	retry := RetryConfig{}
+	retry.setDefaults()
+
+	require.Equal(t, 100*time.Millisecond, retry.backoff(0))
+	require.Equal(t, 200*time.Millisecond, retry.backoff(1))
+	require.Equal(t, 400*time.Millisecond, retry.backoff(2))
+	require.Equal(t, 5*time.Second, retry.backoff(10))
+}
+
+// TestRetryMetrics checks whether retries are counted by reason and whether retried
+// requests are counted by their outcome.
+func TestRetryMetrics(t *testing.T) {
+	tests := []struct {
+		testName     string
+		failures     int
+		wantCounters map[string]float64
+	}{
+		{
+			testName: "Recovered after retries",
+			failures: 2,
+			wantCounters: map[string]float64{
+				"cortex_exporter_retries_total{reason=dial_error}":        2,
+				"cortex_exporter_retry_outcomes_total{outcome=recovered}": 1,
+			},
+		},
+		{
+			testName: "Exhausted retries",
+			failures: 5,
+			wantCounters: map[string]float64{
+				"cortex_exporter_retries_total{reason=dial_error}":        3,
+				"cortex_exporter_retry_outcomes_total{outcome=exhausted}": 1,
+			},
+		},
+		{
+			testName:     "No retries",
+			failures:     0,
+			wantCounters: map[string]float64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+			defer server.Close()
+
+			controller := newSelfMetricsController()
+			client, _ := flakyDialClient(test.failures)
+			exporter := Exporter{
+				config: Config{
+					Endpoint:         server.URL,
+					RetryOnDialError: &RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1},
+					Client:           client,
+					MeterProvider:    controller.Provider(),
+				},
+			}
+			exporter.send(context.Background(), []byte("message"), &ExportResult{})
+
+			require.Equal(t, test.wantCounters, selfMetricValues(t, controller))
+		})
+	}
+}
+
+// TestRetryReason checks whether failed requests are classified by why they failed.
+func TestRetryReason(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}
+
+	require.Equal(t, "", retryReason(http.StatusOK, nil))
+	require.Equal(t, "dial_error", retryReason(0, dialErr))
+	require.Equal(t, "429", retryReason(http.StatusTooManyRequests, fmt.Errorf("429 Too Many Requests")))
+	require.Equal(t, "5xx", retryReason(http.StatusServiceUnavailable, fmt.Errorf("503 Service Unavailable")))
+	require.Equal(t, "", retryReason(http.StatusBadRequest, fmt.Errorf("400 Bad Request")))
+	require.Equal(t, "", retryReason(http.StatusNotImplemented, fmt.Errorf("501 Not Implemented")))
+}
+
+// TestRetryOnStatus checks whether transient failures are retried according to
+// RetryConfig and other failures fail immediately.
+func TestRetryOnStatus(t *testing.T) {
+	tests := []struct {
+		testName      string
+		statusCodes   []int
+		wantRequests  int
+		wantRetries   int
+		expectedError bool
+	}{
+		{
+			testName:     "Service unavailable is retried",
+			statusCodes:  []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantRequests: 2,
+			wantRetries:  1,
+		},
+		{
+			testName:     "All transient failures are retried",
+			statusCodes:  []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusOK},
+			wantRequests: 5,
+			wantRetries:  4,
+		},
+		{
+			testName:      "Retries are exhausted",
+			statusCodes:   []int{500, 500, 500, 500, 500, 500, 500, 500},
+			wantRequests:  5,
+			wantRetries:   4,
+			expectedError: true,
+		},
+		{
+			testName:      "Bad request is not retried",
+			statusCodes:   []int{http.StatusBadRequest, http.StatusOK},
+			wantRequests:  1,
+			expectedError: true,
+		},
+		{
+			testName:      "Unauthorized is not retried",
+			statusCodes:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantRequests:  1,
+			expectedError: true,
+		},
+		{
+			testName:      "Forbidden is not retried",
+			statusCodes:   []int{http.StatusForbidden, http.StatusOK},
+			wantRequests:  1,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			requests := 0
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(test.statusCodes[requests])
+				requests++
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:    server.URL,
+					RetryConfig: &RetryConfig{MaxRetries: 4, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1},
+				},
+			}
+
+			result := ExportResult{}
+			err := exporter.send(context.Background(), []byte("message"), &result)
+			if test.expectedError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.wantRequests, requests)
+			require.Equal(t, test.wantRetries, result.Retries)
+		})
+	}
+}
+
+// TestRetryAfter checks whether the Retry-After header of a 429 response is waited for
+// instead of the backoff, and whether retries stop when the context is done first.
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		testName      string
+		retryAfter    string
+		wantRequests  int
+		expectedError error
+	}{
+		{
+			testName:     "Retry-After is waited for",
+			retryAfter:   "1",
+			wantRequests: 2,
+		},
+		{
+			testName:      "Retry-After after the deadline",
+			retryAfter:    "3600",
+			wantRequests:  1,
+			expectedError: context.DeadlineExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			requests := 0
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				requests++
+				if requests == 1 {
+					rw.Header().Set("Retry-After", test.retryAfter)
+					rw.WriteHeader(http.StatusTooManyRequests)
+				}
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			exporter := Exporter{
+				config: Config{
+					Endpoint:    server.URL,
+					RetryConfig: &RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1},
+				},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			start := time.Now()
+			err := exporter.send(ctx, []byte("message"), &ExportResult{})
+			require.Equal(t, test.expectedError, err)
+			require.Equal(t, test.wantRequests, requests)
+			require.True(t, time.Since(start) >= time.Second)
+		})
+	}
+}
+
+// TestParseRetryAfter checks whether Retry-After values in seconds and as dates are
+// parsed.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	require.Equal(t, time.Minute, parseRetryAfter("Sat, 01 Aug 2020 12:01:00 GMT", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("Sat, 01 Aug 2020 11:00:00 GMT", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("", now))
+}
+
+// TestJitter checks whether jitter subtracts up to half of the duration.
+func TestJitter(t *testing.T) {
+	require.Equal(t, time.Second, jitter(time.Second, func() float64 { return 0 }))
+	require.Equal(t, 750*time.Millisecond, jitter(time.Second, func() float64 { return 0.5 }))
+	require.True(t, jitter(time.Second, func() float64 { return 0.999 }) > 500*time.Millisecond)
+}
+
+// TestMaxPushDuration checks whether a push is aborted once MaxPushDuration has elapsed,
+// even though retries remain.
+func TestMaxPushDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	client, dials := flakyDialClient(100)
+	exporter := Exporter{
+		config: Config{
+			Endpoint: server.URL,
+			RetryOnDialError: &RetryConfig{
+				MaxRetries:      100,
+				InitialInterval: 20 * time.Millisecond,
+				MaxInterval:     20 * time.Millisecond,
+				Multiplier:      1,
+			},
+			MaxPushDuration: 100 * time.Millisecond,
+			Client:          client,
+		},
+	}
+
+	start := time.Now()
+	err := exporter.Export(context.Background(), getValidCheckpointSet(t))
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, time.Since(start) < time.Second)
+	require.Less(t, *dials, 100)
+}
@@ -0,0 +1,33 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import "strings"
+
+// runtimeMetricPrefixes are the prefixes of the sanitized names of runtime and process
+// metrics, such as the ones of the runtime instrumentation and of Prometheus clients.
+var runtimeMetricPrefixes = []string{"go_", "process_", "runtime_"}
+
+// isRuntimeMetric returns whether an instrument name belongs to a runtime or process
+// metric.
+func isRuntimeMetric(name string) bool {
+	name = sanitize(name)
+	for _, prefix := range runtimeMetricPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
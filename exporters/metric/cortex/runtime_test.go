@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	apimetric "go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestDropRuntimeMetrics checks whether records of runtime and process metrics are only
+// dropped when DropRuntimeMetrics is enabled.
+func TestDropRuntimeMetrics(t *testing.T) {
+	var records []export.Record
+	for _, name := range []string{"runtime.go.goroutines", "process_cpu_seconds", "go_gc_duration", "http.requests"} {
+		desc := apimetric.NewDescriptor(name, apimetric.CounterKind, apimetric.Int64NumberKind)
+		records = append(records, newSumRecord(t, &desc, 1, time.Time{}, time.Time{}))
+	}
+
+	tests := []struct {
+		testName           string
+		dropRuntimeMetrics bool
+		wantNames          []string
+	}{
+		{
+			testName:  "Runtime metrics are kept by default",
+			wantNames: []string{"runtime_go_goroutines", "process_cpu_seconds", "go_gc_duration", "http_requests"},
+		},
+		{
+			testName:           "Runtime metrics are dropped when enabled",
+			dropRuntimeMetrics: true,
+			wantNames:          []string{"http_requests"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			exporter := Exporter{config: Config{DropRuntimeMetrics: test.dropRuntimeMetrics}}
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+			require.Nil(t, err)
+
+			var names []string
+			for _, ts := range timeSeries {
+				names = append(names, metricName(ts))
+			}
+			require.ElementsMatch(t, test.wantNames, names)
+		})
+	}
+}
@@ -17,6 +17,8 @@ package cortex
 import (
 	"strings"
 	"unicode"
+
+	"github.com/prometheus/prometheus/prompb"
 )
 
 // This is a copy of opentelemetry-go/sdk/internal/sanitize.go
@@ -37,11 +39,60 @@ func sanitize(s string) string {
 	return s
 }
 
-// converts anything that is not a letter or digit to an underscore
+// converts anything that is not an ASCII letter or digit to an underscore, since
+// Prometheus names must match [a-zA-Z_][a-zA-Z0-9_]*
 func sanitizeRune(r rune) rune {
-	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+	if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
 		return r
 	}
 	// Everything else turns into an underscore
 	return '_'
 }
+
+// validLabelName reports whether a label name matches [a-zA-Z_][a-zA-Z0-9_]*.
+func validLabelName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && sanitizeRune(r) == '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeLabels sanitizes the label names of every TimeSeries that are not valid
+// Prometheus label names, which steps like LabelTransform may introduce, and sorts the
+// labels by name. Cortex rejects requests with invalid or unsorted label names.
+func normalizeLabels(timeSeries []*prompb.TimeSeries) {
+	for _, ts := range timeSeries {
+		for _, label := range ts.Labels {
+			if !validLabelName(label.Name) {
+				label.Name = sanitize(label.Name)
+			}
+		}
+	}
+	sortLabels(timeSeries)
+}
+
+// sanitizeLabelValue replaces invalid UTF-8 sequences with the Unicode replacement
+// character and removes control characters, which backends may reject or mishandle.
+func sanitizeLabelValue(s string) string {
+	s = strings.ToValidUTF8(s, string(unicode.ReplacementChar))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// sanitizeLabelValues sanitizes the label values of every TimeSeries.
+func sanitizeLabelValues(timeSeries []*prompb.TimeSeries) {
+	for _, ts := range timeSeries {
+		for _, label := range ts.Labels {
+			label.Value = sanitizeLabelValue(label.Value)
+		}
+	}
+}
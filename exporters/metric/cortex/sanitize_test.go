@@ -16,6 +16,13 @@ package cortex
 
 import (
 	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/metric"
 )
 
 func TestSanitize(t *testing.T) {
@@ -44,6 +51,11 @@ func TestSanitize(t *testing.T) {
 			input: "/0123456789",
 			want:  "key_0123456789",
 		},
+		{
+			name:  "replace non-ASCII letter",
+			input: "café",
+			want:  "caf_",
+		},
 		{
 			name:  "valid input",
 			input: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_0123456789",
@@ -64,3 +76,79 @@ func TestSanitize(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeLabelValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "replace invalid UTF-8",
+			input: "value\xff\xfe",
+			want:  "value\uFFFD",
+		},
+		{
+			name:  "remove control characters",
+			input: "line1\nline2\t\x00",
+			want:  "line1line2",
+		},
+		{
+			name:  "valid input",
+			input: "välue with spaces/and-symbols",
+			want:  "välue with spaces/and-symbols",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := sanitizeLabelValue(tt.input), tt.want; got != want {
+				t.Errorf("sanitizeLabelValue() = %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+// TestNormalizeLabels checks whether converted series have valid label names sorted by
+// name, including labels with dots and dashes and labels added by LabelTransform.
+func TestNormalizeLabels(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			LabelTransform: func(labels []*prompb.Label) []*prompb.Label {
+				return append(labels, &prompb.Label{Name: "added.by-transform", Value: "1"})
+			},
+		},
+	}
+
+	desc := metric.NewDescriptor("metric.name", metric.CounterKind, metric.Int64NumberKind)
+	record := newSumRecord(t, &desc, 1, time.Time{}, time.Now(),
+		kv.String("http.method", "GET"),
+		kv.String("user-agent", "curl"),
+		kv.String("Zone", "east"),
+		kv.String("1st", "yes"),
+	)
+	timeSeries, err := exporter.convertRecord(record)
+	require.Nil(t, err)
+	require.Len(t, timeSeries, 1)
+
+	require.Equal(t, []*prompb.Label{
+		{Name: "R", Value: "V"},
+		{Name: "Zone", Value: "east"},
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "added_by_transform", Value: "1"},
+		{Name: "http_method", Value: "GET"},
+		{Name: "key_1st", Value: "yes"},
+		{Name: "user_agent", Value: "curl"},
+	}, timeSeries[0].Labels)
+}
+
+// TestValidLabelName checks whether label names are validated against
+// [a-zA-Z_][a-zA-Z0-9_]*.
+func TestValidLabelName(t *testing.T) {
+	require.True(t, validLabelName("__name__"))
+	require.True(t, validLabelName("Label_1"))
+	require.False(t, validLabelName(""))
+	require.False(t, validLabelName("1label"))
+	require.False(t, validLabelName("http.method"))
+	require.False(t, validLabelName("café"))
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// MetricNameSchemaPolicyDrop drops series whose metric name does not match
+	// MetricNameSchema.
+	MetricNameSchemaPolicyDrop = "drop"
+
+	// MetricNameSchemaPolicyError fails a push that contains a series whose metric name
+	// does not match MetricNameSchema.
+	MetricNameSchemaPolicyError = "error"
+)
+
+var (
+	// ErrNonConformingMetricName occurs when a metric name does not match
+	// MetricNameSchema and MetricNameSchemaPolicy is "error".
+	ErrNonConformingMetricName = fmt.Errorf("Metric name does not match the metric name schema")
+)
+
+// anchorMetricNameSchema anchors MetricNameSchema so that it has to match the whole
+// metric name.
+func anchorMetricNameSchema(schema string) string {
+	return "^(?:" + schema + ")$"
+}
+
+// metricNameSchema returns the compiled MetricNameSchema. It is compiled the first time,
+// and Validate ensures that it compiles.
+func (e *Exporter) metricNameSchema() *regexp.Regexp {
+	e.metricNameSchemaOnce.Do(func() {
+		e.metricNameRegex = regexp.MustCompile(anchorMetricNameSchema(e.config.MetricNameSchema))
+	})
+	return e.metricNameRegex
+}
+
+// checkMetricNames checks whether the metric name of every TimeSeries, including suffixes
+// like "_sum", matches MetricNameSchema and handles violations according to
+// MetricNameSchemaPolicy.
+func (e *Exporter) checkMetricNames(timeSeries []*prompb.TimeSeries) ([]*prompb.TimeSeries, error) {
+	schema := e.metricNameSchema()
+
+	res := timeSeries[:0]
+	for _, ts := range timeSeries {
+		if !schema.MatchString(metricName(ts)) {
+			if e.config.MetricNameSchemaPolicy == MetricNameSchemaPolicyError {
+				return nil, ErrNonConformingMetricName
+			}
+			e.addDroppedSeries("metric_name_schema")
+			continue
+		}
+		res = append(res, ts)
+	}
+	return res, nil
+}
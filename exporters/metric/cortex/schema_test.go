@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckMetricNames checks whether series with a metric name that does not match
+// MetricNameSchema are dropped or fail the push depending on MetricNameSchemaPolicy.
+func TestCheckMetricNames(t *testing.T) {
+	tests := []struct {
+		testName      string
+		policy        string
+		wantCounters  map[string]float64
+		expectedError error
+	}{
+		{
+			testName: "Drop",
+			policy:   MetricNameSchemaPolicyDrop,
+			wantCounters: map[string]float64{
+				"cortex_exporter_dropped_series_total{reason=metric_name_schema}": 1,
+			},
+		},
+		{
+			testName:      "Error",
+			policy:        MetricNameSchemaPolicyError,
+			expectedError: ErrNonConformingMetricName,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			config := Config{
+				MetricNameSchema:       "myapp_[a-z_]+",
+				MetricNameSchemaPolicy: test.policy,
+				MeterProvider:          controller.Provider(),
+			}
+			require.Nil(t, config.Validate())
+			exporter := Exporter{config: config}
+
+			timeSeries := []*prompb.TimeSeries{
+				{
+					Labels:  []*prompb.Label{{Name: "__name__", Value: "myapp_requests_total"}},
+					Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+				},
+				{
+					Labels:  []*prompb.Label{{Name: "__name__", Value: "requests_total"}},
+					Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+				},
+			}
+			got, err := exporter.checkMetricNames(timeSeries)
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Len(t, got, 1)
+			require.Equal(t, "myapp_requests_total", metricName(got[0]))
+			require.Equal(t, test.wantCounters, selfMetricValues(t, controller))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+)
+
+const (
+	// CrossScopeNameConflictPolicyError fails a push that contains metrics with the same
+	// name and different types from different instrumentation libraries.
+	CrossScopeNameConflictPolicyError = "error"
+
+	// CrossScopeNameConflictPolicyScopePrefix prefixes the name of a conflicting metric
+	// with the sanitized name of its instrumentation library.
+	CrossScopeNameConflictPolicyScopePrefix = "scope_prefix"
+
+	// CrossScopeNameConflictPolicyFirst keeps the metric that was converted first and
+	// drops the conflicting ones.
+	CrossScopeNameConflictPolicyFirst = "first"
+)
+
+var (
+	// ErrCrossScopeNameConflict occurs when metrics with the same name and different types
+	// are exported by different instrumentation libraries and CrossScopeNameConflictPolicy
+	// is "error".
+	ErrCrossScopeNameConflict = fmt.Errorf("Metrics with the same name and different types are exported by different instrumentation libraries")
+)
+
+// metricType returns the Prometheus type a record is converted to.
+func metricType(record metric.Record) string {
+	agg := record.Aggregation()
+	if _, ok := agg.(aggregation.Histogram); ok {
+		return "histogram"
+	}
+	if _, ok := agg.(aggregation.Distribution); ok {
+		return "summary"
+	}
+	if _, ok := agg.(aggregation.Sum); ok && record.Descriptor().MetricKind().Monotonic() {
+		return "counter"
+	}
+	return "gauge"
+}
+
+// scopeMetric is the instrumentation library and the type of the first metric converted
+// with a name.
+type scopeMetric struct {
+	scope      string
+	metricType string
+}
+
+// scopeConflictResolver detects metrics with the same name and different types from
+// different instrumentation libraries in one collection, which Prometheus cannot store
+// together, and handles them according to CrossScopeNameConflictPolicy.
+type scopeConflictResolver struct {
+	exporter *Exporter
+
+	// metrics holds the first metric converted with each sanitized name.
+	metrics map[string]scopeMetric
+}
+
+// resolve returns the TimeSeries converted from a record, after handling a conflict with
+// a metric converted before.
+func (r *scopeConflictResolver) resolve(record metric.Record, converted []*prompb.TimeSeries) ([]*prompb.TimeSeries, error) {
+	if r.metrics == nil {
+		r.metrics = make(map[string]scopeMetric)
+	}
+
+	name := sanitize(record.Descriptor().Name())
+	current := scopeMetric{
+		scope:      record.Descriptor().InstrumentationName(),
+		metricType: metricType(record),
+	}
+	first, found := r.metrics[name]
+	if !found {
+		r.metrics[name] = current
+		return converted, nil
+	}
+	if first.scope == current.scope || first.metricType == current.metricType {
+		return converted, nil
+	}
+
+	switch r.exporter.config.CrossScopeNameConflictPolicy {
+	case CrossScopeNameConflictPolicyError:
+		return nil, ErrCrossScopeNameConflict
+	case CrossScopeNameConflictPolicyFirst:
+		for range converted {
+			r.exporter.addDroppedSeries("cross_scope_name_conflict")
+		}
+		return nil, nil
+	}
+
+	prefix := sanitize(current.scope) + "_"
+	renamed := make([]*prompb.TimeSeries, len(converted))
+	for i, ts := range converted {
+		renamed[i] = renameTimeSeries(ts, prefix+metricName(ts))
+	}
+	return renamed, nil
+}
@@ -0,0 +1,98 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestCrossScopeNameConflict checks whether metrics with the same name and different types
+// from different instrumentation libraries are handled according to
+// CrossScopeNameConflictPolicy.
+func TestCrossScopeNameConflict(t *testing.T) {
+	counter := metric.NewDescriptor("requests", metric.CounterKind, metric.Int64NumberKind,
+		metric.WithInstrumentationName("server"))
+	gauge := metric.NewDescriptor("requests", metric.UpDownCounterKind, metric.Int64NumberKind,
+		metric.WithInstrumentationName("go.client"))
+	sameType := metric.NewDescriptor("requests", metric.CounterKind, metric.Int64NumberKind,
+		metric.WithInstrumentationName("go.client"))
+
+	tests := []struct {
+		testName      string
+		policy        string
+		second        metric.Descriptor
+		wantValues    map[string]float64
+		expectedError error
+	}{
+		{
+			testName:   "No policy",
+			policy:     "",
+			second:     gauge,
+			wantValues: map[string]float64{"requests": 2},
+		},
+		{
+			testName:      "Error",
+			policy:        CrossScopeNameConflictPolicyError,
+			second:        gauge,
+			expectedError: ErrCrossScopeNameConflict,
+		},
+		{
+			testName:   "Scope prefix",
+			policy:     CrossScopeNameConflictPolicyScopePrefix,
+			second:     gauge,
+			wantValues: map[string]float64{"requests": 1, "go_client_requests": 2},
+		},
+		{
+			testName:   "First",
+			policy:     CrossScopeNameConflictPolicyFirst,
+			second:     gauge,
+			wantValues: map[string]float64{"requests": 1},
+		},
+		{
+			testName:   "Same type is no conflict",
+			policy:     CrossScopeNameConflictPolicyError,
+			second:     sameType,
+			wantValues: map[string]float64{"requests": 2},
+		},
+	}
+
+	for _, test := range tests {
+		for _, concurrency := range []int{0, 2} {
+			t.Run(test.testName, func(t *testing.T) {
+				exporter := Exporter{
+					config: Config{
+						CrossScopeNameConflictPolicy: test.policy,
+						ConvertConcurrency:           concurrency,
+					},
+				}
+				records := []export.Record{
+					newSumRecord(t, &counter, 1, time.Time{}, time.Time{}),
+					newSumRecord(t, &test.second, 2, time.Time{}, time.Time{}),
+				}
+
+				timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: records})
+				require.Equal(t, test.expectedError, err)
+				if test.expectedError == nil {
+					require.Equal(t, test.wantValues, timeSeriesValues(timeSeries))
+				}
+			})
+		}
+	}
+}
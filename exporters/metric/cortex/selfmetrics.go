@@ -0,0 +1,222 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/kv"
+	apimetric "go.opentelemetry.io/otel/api/metric"
+)
+
+// instrumentationName is the name of the Meter the Exporter reports on itself with.
+const instrumentationName = "go.opentelemetry.io/contrib/exporters/metric/cortex"
+
+// selfMetrics holds the instruments the Exporter uses to report on itself.
+type selfMetrics struct {
+	droppedSamples apimetric.Int64Counter
+	droppedSeries  apimetric.Int64Counter
+	longLabelNames apimetric.Int64Counter
+	retries        apimetric.Int64Counter
+	retryOutcomes  apimetric.Int64Counter
+	timestampSkew  apimetric.Float64ValueRecorder
+	compression    apimetric.Float64ValueRecorder
+	seriesWarnings apimetric.Int64Counter
+	certExpiry     apimetric.Int64Counter
+	resourceChange apimetric.Int64Counter
+	pausedPushes   apimetric.Int64Counter
+	samplesSent    apimetric.Int64Counter
+	failedPushes   apimetric.Int64Counter
+	pushDuration   apimetric.Float64ValueRecorder
+}
+
+// metrics returns the instruments the Exporter reports on itself with. They are created
+// from the MeterProvider in the Config, or the global one if it is not set, the first
+// time they are used.
+func (e *Exporter) metrics() *selfMetrics {
+	e.selfMetricsOnce.Do(func() {
+		provider := e.config.MeterProvider
+		if provider == nil {
+			provider = global.MeterProvider()
+		}
+		// Metrics recorded with the Provider of the Controller that pushes to the
+		// Exporter would be sent by the Exporter itself and count their own pushes.
+		if e.pipelineProvider != nil && provider == e.pipelineProvider {
+			provider = apimetric.NoopProvider{}
+		}
+		meter := apimetric.Must(provider.Meter(instrumentationName))
+
+		e.selfMetrics = &selfMetrics{
+			droppedSamples: meter.NewInt64Counter(
+				"cortex_exporter_dropped_samples_total",
+				apimetric.WithDescription("Number of samples the exporter dropped instead of sending"),
+			),
+			droppedSeries: meter.NewInt64Counter(
+				"cortex_exporter_dropped_series_total",
+				apimetric.WithDescription("Number of series the exporter dropped instead of sending"),
+			),
+			longLabelNames: meter.NewInt64Counter(
+				"cortex_exporter_long_label_names_total",
+				apimetric.WithDescription("Number of labels truncated or dropped because their name was too long"),
+			),
+			retries: meter.NewInt64Counter(
+				"cortex_exporter_retries_total",
+				apimetric.WithDescription("Number of times a request was retried by reason"),
+			),
+			retryOutcomes: meter.NewInt64Counter(
+				"cortex_exporter_retry_outcomes_total",
+				apimetric.WithDescription("Number of retried requests that eventually succeeded or gave up"),
+			),
+			timestampSkew: meter.NewFloat64ValueRecorder(
+				"cortex_exporter_max_timestamp_skew_seconds",
+				apimetric.WithDescription("Largest difference between a sample timestamp and the wall clock in a push"),
+			),
+			compression: meter.NewFloat64ValueRecorder(
+				"cortex_exporter_compression_ratio",
+				apimetric.WithDescription("Ratio of the uncompressed to the compressed size of the requests of a push"),
+			),
+			seriesWarnings: meter.NewInt64Counter(
+				"cortex_exporter_series_warnings_total",
+				apimetric.WithDescription("Number of pushes with more series than the warning threshold"),
+			),
+			certExpiry: meter.NewInt64Counter(
+				"cortex_exporter_cert_expiry_warnings_total",
+				apimetric.WithDescription("Number of times the client certificate was found to expire soon"),
+			),
+			resourceChange: meter.NewInt64Counter(
+				"cortex_exporter_resource_changes_total",
+				apimetric.WithDescription("Number of pushes whose resource differed from the one of the previous push"),
+			),
+			pausedPushes: meter.NewInt64Counter(
+				"cortex_exporter_paused_pushes_total",
+				apimetric.WithDescription("Number of pushes whose metrics were dropped because the Exporter was paused"),
+			),
+			samplesSent: meter.NewInt64Counter(
+				"cortex_exporter_samples_sent_total",
+				apimetric.WithDescription("Number of samples in requests that Cortex accepted"),
+			),
+			failedPushes: meter.NewInt64Counter(
+				"cortex_exporter_failed_pushes_total",
+				apimetric.WithDescription("Number of pushes that failed with an error"),
+			),
+			pushDuration: meter.NewFloat64ValueRecorder(
+				"cortex_exporter_push_duration_seconds",
+				apimetric.WithDescription("Time a push took, including conversion and retries"),
+			),
+		}
+	})
+	return e.selfMetrics
+}
+
+// metricLabels returns the labels of a self-metric measurement. Measurements are labeled
+// with the Name in the Config, if set, so that several Exporters can be told apart.
+func (e *Exporter) metricLabels(labels ...kv.KeyValue) []kv.KeyValue {
+	if e.config.Name != "" {
+		labels = append(labels, kv.String("exporter", e.config.Name))
+	}
+	return labels
+}
+
+// addDroppedSamples counts samples that were dropped for a reason.
+func (e *Exporter) addDroppedSamples(count int, reason string) {
+	if count == 0 {
+		return
+	}
+	e.metrics().droppedSamples.Add(context.Background(), int64(count), e.metricLabels(kv.String("reason", reason))...)
+}
+
+// addDroppedSeries counts a series that was dropped for a reason.
+func (e *Exporter) addDroppedSeries(reason string) {
+	e.metrics().droppedSeries.Add(context.Background(), 1, e.metricLabels(kv.String("reason", reason))...)
+}
+
+// addLongLabelName counts a label that was truncated or dropped because its name was too
+// long.
+func (e *Exporter) addLongLabelName(action string) {
+	e.metrics().longLabelNames.Add(context.Background(), 1, e.metricLabels(kv.String("action", action))...)
+}
+
+// addRetry counts a retry of a request.
+func (e *Exporter) addRetry(reason string) {
+	e.metrics().retries.Add(context.Background(), 1, e.metricLabels(kv.String("reason", reason))...)
+}
+
+// addRetryOutcome counts whether a request that was retried eventually succeeded. Requests
+// that were not retried are not counted.
+func (e *Exporter) addRetryOutcome(retries int, err error) {
+	if retries == 0 {
+		return
+	}
+	outcome := "recovered"
+	if err != nil {
+		outcome = "exhausted"
+	}
+	e.metrics().retryOutcomes.Add(context.Background(), 1, e.metricLabels(kv.String("outcome", outcome))...)
+}
+
+// addSeriesWarning counts a push with more series than SeriesWarnThreshold.
+func (e *Exporter) addSeriesWarning() {
+	e.metrics().seriesWarnings.Add(context.Background(), 1, e.metricLabels()...)
+}
+
+// addCertExpiryWarning counts a check that found the client certificate to expire within
+// CertExpiryWarnWindow.
+func (e *Exporter) addCertExpiryWarning() {
+	e.metrics().certExpiry.Add(context.Background(), 1, e.metricLabels()...)
+}
+
+// addResourceChange counts a push whose resource differed from the previous one.
+func (e *Exporter) addResourceChange() {
+	e.metrics().resourceChange.Add(context.Background(), 1, e.metricLabels()...)
+}
+
+// addPausedPush counts a push whose metrics were dropped because the Exporter was paused.
+func (e *Exporter) addPausedPush() {
+	e.metrics().pausedPushes.Add(context.Background(), 1, e.metricLabels()...)
+}
+
+// addSamplesSent counts the samples of TimeSeries that were sent successfully.
+func (e *Exporter) addSamplesSent(timeSeries []*prompb.TimeSeries) {
+	samples := 0
+	for _, ts := range timeSeries {
+		samples += len(ts.Samples)
+	}
+	if samples == 0 {
+		return
+	}
+	e.metrics().samplesSent.Add(context.Background(), int64(samples), e.metricLabels()...)
+}
+
+// recordPush records how long a push took and counts it if it failed.
+func (e *Exporter) recordPush(duration time.Duration, err error) {
+	e.metrics().pushDuration.Record(context.Background(), duration.Seconds(), e.metricLabels()...)
+	if err != nil {
+		e.metrics().failedPushes.Add(context.Background(), 1, e.metricLabels()...)
+	}
+}
+
+// recordCompressionRatio records the ratio of the uncompressed to the compressed size of
+// the requests of a push. Pushes without requests are not recorded.
+func (e *Exporter) recordCompressionRatio(result ExportResult) {
+	if result.BytesSent == 0 {
+		return
+	}
+	ratio := float64(result.UncompressedBytes) / float64(result.BytesSent)
+	e.metrics().compression.Record(context.Background(), ratio, e.metricLabels()...)
+}
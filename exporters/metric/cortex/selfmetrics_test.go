@@ -0,0 +1,111 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/global"
+)
+
+// TestPushMetrics checks whether the samples of accepted requests, failed pushes, and
+// push durations are recorded.
+func TestPushMetrics(t *testing.T) {
+	requests := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests > 1 {
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			Endpoint:      server.URL,
+			MeterProvider: controller.Provider(),
+		},
+	}
+
+	require.Nil(t, exporter.Export(context.Background(), getHistogramCheckpoint(t)))
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+
+	values := selfMetricValues(t, controller)
+	require.Equal(t, float64(6), values["cortex_exporter_samples_sent_total{}"])
+	require.Equal(t, float64(1), values["cortex_exporter_failed_pushes_total{}"])
+	require.True(t, values["cortex_exporter_push_duration_seconds{}"] > 0)
+}
+
+// TestSelfMetricsProvider checks whether self-metrics are recorded with the global
+// Provider when MeterProvider is not set and never with the Provider of the Exporter's
+// own pipeline.
+func TestSelfMetricsProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		testName      string
+		meterProvider bool
+		ownPipeline   bool
+		wantRecorded  bool
+	}{
+		{
+			testName:      "MeterProvider",
+			meterProvider: true,
+			wantRecorded:  true,
+		},
+		{
+			testName:     "Global Provider",
+			wantRecorded: true,
+		},
+		{
+			testName:      "MeterProvider of own pipeline",
+			meterProvider: true,
+			ownPipeline:   true,
+			wantRecorded:  false,
+		},
+		{
+			testName:     "Global Provider of own pipeline",
+			ownPipeline:  true,
+			wantRecorded: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			controller := newSelfMetricsController()
+			exporter := Exporter{config: Config{Endpoint: server.URL}}
+			if test.meterProvider {
+				exporter.config.MeterProvider = controller.Provider()
+			} else {
+				global.SetMeterProvider(controller.Provider())
+			}
+			if test.ownPipeline {
+				exporter.pipelineProvider = controller.Provider()
+			}
+
+			require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+			_, recorded := selfMetricValues(t, controller)["cortex_exporter_samples_sent_total{}"]
+			require.Equal(t, test.wantRecorded, recorded)
+		})
+	}
+}
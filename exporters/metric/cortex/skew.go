@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// recordTimestampSkew records the largest difference between a sample timestamp and the
+// wall clock in a push, in seconds. Samples in the future have a positive skew, which
+// helps to catch clock problems before Cortex rejects the samples.
+func (e *Exporter) recordTimestampSkew(timeSeries []*prompb.TimeSeries, now time.Time) {
+	nowMillis := now.UnixNano() / int64(time.Millisecond)
+
+	found := false
+	var maxSkew int64
+	for _, ts := range timeSeries {
+		for _, sample := range ts.Samples {
+			skew := sample.Timestamp - nowMillis
+			if !found || math.Abs(float64(skew)) > math.Abs(float64(maxSkew)) {
+				maxSkew = skew
+				found = true
+			}
+		}
+	}
+	if !found {
+		return
+	}
+
+	seconds := float64(maxSkew) / float64(time.Second/time.Millisecond)
+	e.metrics().timestampSkew.Record(context.Background(), seconds, e.metricLabels()...)
+}
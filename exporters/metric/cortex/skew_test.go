@@ -0,0 +1,46 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecordTimestampSkew checks whether the largest skew of a push is recorded, with a
+// positive value for a future-dated sample.
+func TestRecordTimestampSkew(t *testing.T) {
+	controller := newSelfMetricsController()
+	exporter := Exporter{config: Config{MeterProvider: controller.Provider()}}
+
+	now := time.Unix(1000, 0)
+	timeSeries := []*prompb.TimeSeries{
+		{
+			Labels: []*prompb.Label{{Name: "__name__", Value: "metric_name"}},
+			Samples: []prompb.Sample{
+				{Value: 1, Timestamp: 990 * 1000},
+				{Value: 2, Timestamp: 1030 * 1000},
+			},
+		},
+	}
+	exporter.recordTimestampSkew(timeSeries, now)
+
+	require.Equal(t, map[string]float64{
+		"cortex_exporter_max_timestamp_skew_seconds{}": 30,
+	}, selfMetricValues(t, controller))
+}
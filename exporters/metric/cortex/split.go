@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// splitTimeSeries splits TimeSeries into batches of at most maxSeries TimeSeries and at
+// most maxSamples samples, starting a new batch whenever adding a TimeSeries would exceed
+// either limit. A TimeSeries with more than maxSamples samples is sent in a batch of its
+// own. Limits that are not positive are not applied.
+func splitTimeSeries(timeSeries []*prompb.TimeSeries, maxSeries, maxSamples int) [][]*prompb.TimeSeries {
+	if maxSeries <= 0 && maxSamples <= 0 {
+		return [][]*prompb.TimeSeries{timeSeries}
+	}
+
+	var batches [][]*prompb.TimeSeries
+	start, samples := 0, 0
+	for i, ts := range timeSeries {
+		full := maxSeries > 0 && i-start >= maxSeries
+		full = full || (maxSamples > 0 && samples+len(ts.Samples) > maxSamples)
+		if full && i > start {
+			batches = append(batches, timeSeries[start:i])
+			start, samples = i, 0
+		}
+		samples += len(ts.Samples)
+	}
+	return append(batches, timeSeries[start:])
+}
+
+// sendTimeSeries sends TimeSeries to Cortex in requests of at most MaxSeriesPerRequest
+// TimeSeries and MaxSamplesPerRequest samples, waiting InterRequestDelay between
+// requests. With TenantSharding, the TimeSeries of every tenant are sent in requests of
+// their own. It stops at the first request that fails.
+func (e *Exporter) sendTimeSeries(ctx context.Context, timeSeries []*prompb.TimeSeries, result *ExportResult) error {
+	if e.config.TenantSharding == nil {
+		return e.sendTenantTimeSeries(ctx, timeSeries, result)
+	}
+
+	tenants, groups := e.shardByTenant(timeSeries)
+	for i, tenant := range tenants {
+		if i > 0 && e.config.InterRequestDelay > 0 {
+			if err := sleep(ctx, e.config.InterRequestDelay); err != nil {
+				return err
+			}
+		}
+		if err := e.sendTenantTimeSeries(withTenant(ctx, tenant), groups[i], result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendTenantTimeSeries sends the TimeSeries of a single tenant like sendTimeSeries.
+func (e *Exporter) sendTenantTimeSeries(ctx context.Context, timeSeries []*prompb.TimeSeries, result *ExportResult) error {
+	for i, batch := range splitTimeSeries(timeSeries, e.config.MaxSeriesPerRequest, e.config.MaxSamplesPerRequest) {
+		if i > 0 && e.config.InterRequestDelay > 0 {
+			if err := sleep(ctx, e.config.InterRequestDelay); err != nil {
+				return err
+			}
+		}
+
+		if err := e.sendBatch(ctx, batch, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendBatch sends TimeSeries to Cortex in a single request. If AutoSplitOnFailure is set
+// and Cortex rejects the request as too large, the TimeSeries are split in halves that
+// are sent in separate requests, down to requests of a single TimeSeries.
+func (e *Exporter) sendBatch(ctx context.Context, batch []*prompb.TimeSeries, result *ExportResult) error {
+	var size int
+	var err error
+	if e.config.BodyStrategy == BodyStrategyStream {
+		var streamedSize int64
+		err = e.sendBody(ctx, func() io.Reader { return e.streamMessage(batch, &streamedSize) }, result)
+		size = int(atomic.LoadInt64(&streamedSize))
+	} else {
+		var message []byte
+		if message, err = e.buildMessage(batch); err != nil {
+			return err
+		}
+		size = len(message)
+		err = e.send(ctx, message, result)
+	}
+	uncompressed := (&prompb.WriteRequest{Timeseries: batch}).Size()
+	result.SeriesSent += len(batch)
+	result.BytesSent += size
+	result.UncompressedBytes += uncompressed
+	if err == nil {
+		e.addSamplesSent(batch)
+	}
+
+	if err == nil || !e.config.AutoSplitOnFailure || result.StatusCode != http.StatusRequestEntityTooLarge || len(batch) < 2 {
+		return err
+	}
+
+	// The halves are counted when they are sent instead of the rejected request.
+	result.SeriesSent -= len(batch)
+	result.BytesSent -= size
+	result.UncompressedBytes -= uncompressed
+	half := len(batch) / 2
+	if err := e.sendBatch(ctx, batch[:half], result); err != nil {
+		return err
+	}
+	return e.sendBatch(ctx, batch[half:], result)
+}
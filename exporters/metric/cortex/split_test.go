@@ -0,0 +1,179 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// makeTimeSeries returns n TimeSeries with distinct names.
+func makeTimeSeries(n int) []*prompb.TimeSeries {
+	timeSeries := make([]*prompb.TimeSeries, n)
+	for i := range timeSeries {
+		timeSeries[i] = &prompb.TimeSeries{
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "metric_" + strconv.Itoa(i)}},
+			Samples: []prompb.Sample{{Value: float64(i), Timestamp: 1000}},
+		}
+	}
+	return timeSeries
+}
+
+// TestSplitTimeSeries checks whether TimeSeries are split into batches of at most the
+// maximum number of series and samples without losing or reordering any of them.
+func TestSplitTimeSeries(t *testing.T) {
+	tests := []struct {
+		testName   string
+		samples    []int
+		maxSeries  int
+		maxSamples int
+		wantSizes  []int
+	}{
+		{"No maximum", []int{1, 1, 1, 1, 1}, 0, 0, []int{5}},
+		{"Fewer series than maximum", []int{1, 1}, 5, 0, []int{2}},
+		{"Exact multiple of maximum", []int{1, 1, 1, 1}, 2, 0, []int{2, 2}},
+		{"Remainder in last batch", []int{1, 1, 1, 1, 1}, 2, 0, []int{2, 2, 1}},
+		{"Sample limit", []int{3, 3, 3, 1}, 0, 6, []int{2, 2}},
+		{"Sample limit hit first", []int{4, 4, 1, 1, 1}, 3, 5, []int{1, 2, 2}},
+		{"Series limit hit first", []int{1, 1, 1, 1, 1}, 2, 5, []int{2, 2, 1}},
+		{"Series above sample limit", []int{1, 8, 1}, 0, 5, []int{1, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			timeSeries := makeTimeSeries(len(test.samples))
+			for i, n := range test.samples {
+				for len(timeSeries[i].Samples) < n {
+					timeSeries[i].Samples = append(timeSeries[i].Samples, prompb.Sample{Value: 1, Timestamp: int64(len(timeSeries[i].Samples))})
+				}
+			}
+			batches := splitTimeSeries(timeSeries, test.maxSeries, test.maxSamples)
+
+			var sizes []int
+			var joined []*prompb.TimeSeries
+			for _, batch := range batches {
+				sizes = append(sizes, len(batch))
+				joined = append(joined, batch...)
+			}
+			require.Equal(t, test.wantSizes, sizes)
+			require.Equal(t, timeSeries, joined)
+		})
+	}
+}
+
+// TestMaxSamplesPerRequest checks whether the samples of a histogram are split into
+// several requests when they exceed MaxSamplesPerRequest.
+func TestMaxSamplesPerRequest(t *testing.T) {
+	var received []int
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		samples := 0
+		for _, ts := range writeRequest.Timeseries {
+			samples += len(ts.Samples)
+		}
+		received = append(received, samples)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:             server.URL,
+			MaxSeriesPerRequest:  5,
+			MaxSamplesPerRequest: 4,
+		},
+	}
+
+	// The histogram is converted to 6 series with a sample each.
+	result, err := exporter.ExportWithResult(context.Background(), getHistogramCheckpoint(t))
+	require.Nil(t, err)
+	require.Equal(t, []int{4, 2}, received)
+	require.Equal(t, 6, result.SeriesSent)
+}
+
+// TestInterRequestDelay checks whether InterRequestDelay is waited between the requests
+// of a single push.
+func TestInterRequestDelay(t *testing.T) {
+	var received []time.Time
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		received = append(received, time.Now())
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:            server.URL,
+			MaxSeriesPerRequest: 2,
+			InterRequestDelay:   50 * time.Millisecond,
+		},
+	}
+
+	var result ExportResult
+	require.Nil(t, exporter.sendTimeSeries(context.Background(), makeTimeSeries(3), &result))
+	require.Len(t, received, 2)
+	require.GreaterOrEqual(t, int64(received[1].Sub(received[0])), int64(50*time.Millisecond))
+	require.Equal(t, 3, result.SeriesSent)
+}
+
+// TestAutoSplitOnFailure checks whether a push rejected as too large is split in halves
+// that are sent in separate requests.
+func TestAutoSplitOnFailure(t *testing.T) {
+	var sizes []int
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		if len(writeRequest.Timeseries) > 2 {
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		sizes = append(sizes, len(writeRequest.Timeseries))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:           server.URL,
+			AutoSplitOnFailure: true,
+		},
+	}
+
+	var result ExportResult
+	require.Nil(t, exporter.sendTimeSeries(context.Background(), makeTimeSeries(5), &result))
+	require.Equal(t, []int{2, 1, 2}, sizes)
+	require.Equal(t, 5, result.SeriesSent)
+
+	// Without AutoSplitOnFailure, the push fails.
+	exporter.config.AutoSplitOnFailure = false
+	sizes = nil
+	require.Error(t, exporter.sendTimeSeries(context.Background(), makeTimeSeries(5), &ExportResult{}))
+	require.Empty(t, sizes)
+}
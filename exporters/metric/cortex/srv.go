@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoSRVTargets occurs when UseSRVDiscovery is set and the SRV lookup of the host
+	// of the endpoint returns no targets.
+	ErrNoSRVTargets = fmt.Errorf("SRV lookup returned no targets")
+)
+
+// srvResolver looks up SRV records. It is implemented by net.Resolver.
+type srvResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// srvEndpoint returns the endpoint with its host replaced by a target of the SRV records
+// of the host. The records are looked up again after SRVRefreshInterval, and a target is
+// picked for every request, so requests are balanced across the targets.
+func (e *Exporter) srvEndpoint() (string, error) {
+	endpoint, err := url.Parse(e.config.Endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	targets, err := e.lookupSRVTargets(endpoint.Hostname())
+	if err != nil {
+		return "", err
+	}
+	target := pickSRVTarget(targets, rand.Intn)
+
+	endpoint.Host = net.JoinHostPort(strings.TrimSuffix(target.Target, "."), strconv.Itoa(int(target.Port)))
+	return endpoint.String(), nil
+}
+
+// lookupSRVTargets returns the SRV records of a name, which are cached for
+// SRVRefreshInterval. The cached records are kept when a refresh fails.
+func (e *Exporter) lookupSRVTargets(name string) ([]*net.SRV, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.srvTargets != nil && time.Since(e.srvRefreshed) < e.config.SRVRefreshInterval {
+		return e.srvTargets, nil
+	}
+
+	resolver := e.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx := context.Background()
+	if e.config.RemoteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.RemoteTimeout)
+		defer cancel()
+	}
+
+	// An empty service and protocol look up the name as it is, such as
+	// _cortex._tcp.example.com.
+	_, targets, err := resolver.LookupSRV(ctx, "", "", name)
+	if err == nil && len(targets) == 0 {
+		err = ErrNoSRVTargets
+	}
+	if err != nil {
+		if e.srvTargets != nil {
+			e.logf("Could not refresh SRV records of %s, using the previous targets: %v", name, err)
+			return e.srvTargets, nil
+		}
+		return nil, err
+	}
+
+	e.srvTargets = targets
+	e.srvRefreshed = time.Now()
+	return targets, nil
+}
+
+// pickSRVTarget picks a target as described in RFC 2782: among the targets with the
+// lowest priority, targets are picked with a probability proportional to their weight.
+// random returns a random number in [0, n).
+func pickSRVTarget(targets []*net.SRV, random func(n int) int) *net.SRV {
+	var candidates []*net.SRV
+	for _, target := range targets {
+		if len(candidates) > 0 && target.Priority > candidates[0].Priority {
+			continue
+		}
+		if len(candidates) > 0 && target.Priority < candidates[0].Priority {
+			candidates = candidates[:0]
+		}
+		candidates = append(candidates, target)
+	}
+
+	total := 0
+	for _, target := range candidates {
+		total += int(target.Weight)
+	}
+	if total == 0 {
+		return candidates[random(len(candidates))]
+	}
+
+	n := random(total)
+	for _, target := range candidates {
+		n -= int(target.Weight)
+		if n < 0 {
+			return target
+		}
+	}
+	return candidates[len(candidates)-1]
+}
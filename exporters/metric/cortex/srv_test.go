@@ -0,0 +1,109 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver returns the same SRV records for every lookup and counts the lookups.
+type fakeResolver struct {
+	targets []*net.SRV
+	lookups int
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.lookups++
+	return "", r.targets, nil
+}
+
+// TestSRVDiscovery checks whether the host of the endpoint is replaced by a target with
+// the lowest priority, and whether the records are cached for SRVRefreshInterval.
+func TestSRVDiscovery(t *testing.T) {
+	resolver := &fakeResolver{
+		targets: []*net.SRV{
+			{Target: "backup.example.com.", Port: 9009, Priority: 20, Weight: 100},
+			{Target: "cortex-1.example.com.", Port: 9009, Priority: 10, Weight: 50},
+			{Target: "cortex-2.example.com.", Port: 9009, Priority: 10, Weight: 50},
+		},
+	}
+	exporter := Exporter{
+		config: Config{
+			Endpoint:           "https://_cortex._tcp.example.com/api/prom/push",
+			UseSRVDiscovery:    true,
+			SRVRefreshInterval: time.Minute,
+		},
+		resolver: resolver,
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		requestURL, err := exporter.requestURL()
+		require.NoError(t, err)
+		seen[requestURL] = true
+	}
+	require.Equal(t, map[string]bool{
+		"https://cortex-1.example.com:9009/api/prom/push": true,
+		"https://cortex-2.example.com:9009/api/prom/push": true,
+	}, seen)
+	require.Equal(t, 1, resolver.lookups)
+}
+
+// TestPickSRVTarget checks whether targets are picked by priority and then by weight.
+func TestPickSRVTarget(t *testing.T) {
+	targets := []*net.SRV{
+		{Target: "a", Priority: 10, Weight: 1},
+		{Target: "b", Priority: 10, Weight: 3},
+		{Target: "c", Priority: 20, Weight: 100},
+	}
+
+	tests := []struct {
+		random     int
+		wantTarget string
+	}{
+		{random: 0, wantTarget: "a"},
+		{random: 1, wantTarget: "b"},
+		{random: 3, wantTarget: "b"},
+	}
+	for _, test := range tests {
+		random := func(n int) int {
+			require.Equal(t, 4, n)
+			return test.random
+		}
+		require.Equal(t, test.wantTarget, pickSRVTarget(targets, random).Target)
+	}
+
+	// Targets without a weight are picked uniformly.
+	unweighted := []*net.SRV{{Target: "a"}, {Target: "b"}}
+	require.Equal(t, "b", pickSRVTarget(unweighted, func(n int) int { return n - 1 }).Target)
+}
+
+// TestSRVDiscoveryNoTargets checks whether a lookup without targets fails.
+func TestSRVDiscoveryNoTargets(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			Endpoint:        "https://_cortex._tcp.example.com/api/prom/push",
+			UseSRVDiscovery: true,
+		},
+		resolver: &fakeResolver{},
+	}
+	_, err := exporter.requestURL()
+	require.Equal(t, ErrNoSRVTargets, err)
+}
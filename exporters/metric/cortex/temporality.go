@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+const (
+	// TemporalityMismatchPolicyError fails a push that contains a delta sum.
+	TemporalityMismatchPolicyError = "error"
+
+	// TemporalityMismatchPolicyConvert adds the value of delta sums to the total of
+	// the previous pushes, so that cumulative sums are sent.
+	TemporalityMismatchPolicyConvert = "convert"
+
+	// TemporalityMismatchPolicyPassthrough sends delta sums as they are and logs a
+	// warning.
+	TemporalityMismatchPolicyPassthrough = "passthrough"
+)
+
+var (
+	// ErrTemporalityMismatch occurs when a monotonic sum is delivered with delta
+	// temporality and TemporalityMismatchPolicy is "error".
+	ErrTemporalityMismatch = fmt.Errorf("Monotonic sum has delta temporality, but Cortex expects cumulative sums")
+)
+
+// seriesStateRetention is the number of push intervals for which state the Exporter keeps
+// about a series between pushes is retained after the series was last seen, so that a
+// series missing from a few pushes keeps it.
+const seriesStateRetention = 10
+
+// sumState holds what the Exporter remembers about a monotonic sum between pushes.
+type sumState struct {
+	start time.Time
+	total float64
+	seen  time.Time
+}
+
+// seriesStateTTL returns how long state about a series is retained after the series was
+// last seen.
+func (e *Exporter) seriesStateTTL() time.Duration {
+	interval := e.config.PushInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return seriesStateRetention * interval
+}
+
+// checkTemporality detects monotonic sums delivered with delta temporality, which Cortex
+// does not expect, and handles them according to TemporalityMismatchPolicy. A sum is
+// considered a delta if its start time advanced since the previous push. Note that a
+// cumulative sum that is reset looks the same.
+func (e *Exporter) checkTemporality(record metric.Record, tSeries *prompb.TimeSeries) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.sumStates == nil {
+		e.sumStates = make(map[string]sumState)
+	}
+
+	key := seriesKey(tSeries.Labels)
+	previous, found := e.sumStates[key]
+	delta := found && record.StartTime().After(previous.start)
+	state := sumState{start: record.StartTime(), total: tSeries.Samples[0].Value, seen: record.EndTime()}
+
+	if delta {
+		switch e.config.TemporalityMismatchPolicy {
+		case TemporalityMismatchPolicyError:
+			return ErrTemporalityMismatch
+		case TemporalityMismatchPolicyConvert:
+			state.total += previous.total
+			tSeries.Samples[0].Value = state.total
+		case TemporalityMismatchPolicyPassthrough:
+			e.logf("Sum %s has delta temporality and is sent as it is.", record.Descriptor().Name())
+		}
+	}
+
+	e.sumStates[key] = state
+	return nil
+}
+
+// evictSumStates removes the state of sums that were not seen for seriesStateTTL, relative
+// to the newest sum seen, since their label sets may no longer exist.
+func (e *Exporter) evictSumStates() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var newest time.Time
+	for _, state := range e.sumStates {
+		if state.seen.After(newest) {
+			newest = state.seen
+		}
+	}
+	for key, state := range e.sumStates {
+		if newest.Sub(state.seen) >= e.seriesStateTTL() {
+			delete(e.sumStates, key)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+)
+
+// TestCheckTemporality checks whether counters delivered with delta temporality fail the
+// push, are converted to cumulative sums, or are sent as they are depending on
+// TemporalityMismatchPolicy.
+func TestCheckTemporality(t *testing.T) {
+	tests := []struct {
+		testName      string
+		policy        string
+		wantValue     float64
+		wantLog       bool
+		expectedError error
+	}{
+		{
+			testName:      "Error",
+			policy:        TemporalityMismatchPolicyError,
+			expectedError: ErrTemporalityMismatch,
+		},
+		{
+			testName:  "Convert",
+			policy:    TemporalityMismatchPolicyConvert,
+			wantValue: 8,
+		},
+		{
+			testName:  "Passthrough",
+			policy:    TemporalityMismatchPolicyPassthrough,
+			wantValue: 3,
+			wantLog:   true,
+		},
+	}
+
+	// The second record starts where the first one ended, like a delta sum.
+	desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+	first := newSumRecord(t, &desc, 5, time.Unix(0, 0), time.Unix(10, 0))
+	second := newSumRecord(t, &desc, 3, time.Unix(10, 0), time.Unix(20, 0))
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var logs bytes.Buffer
+			exporter := Exporter{
+				config: Config{
+					TemporalityMismatchPolicy: test.policy,
+					Logger:                    log.New(&logs, "", 0),
+				},
+			}
+
+			timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{first}})
+			require.Nil(t, err)
+			require.Equal(t, float64(5), timeSeries[0].Samples[0].Value)
+
+			timeSeries, err = exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{second}})
+			if test.expectedError != nil {
+				require.Equal(t, test.expectedError, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, test.wantValue, timeSeries[0].Samples[0].Value)
+			require.Equal(t, test.wantLog, logs.Len() > 0)
+		})
+	}
+}
+
+// TestCheckTemporalityCumulative checks whether cumulative counters are sent as they are.
+func TestCheckTemporalityCumulative(t *testing.T) {
+	desc := metric.NewDescriptor("metric_name", metric.CounterKind, metric.Int64NumberKind)
+	exporter := Exporter{config: Config{TemporalityMismatchPolicy: TemporalityMismatchPolicyError}}
+
+	for i, value := range []int64{5, 8} {
+		record := newSumRecord(t, &desc, value, time.Unix(0, 0), time.Unix(int64(i+1)*10, 0))
+		timeSeries, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{record}})
+		require.Nil(t, err)
+		require.Equal(t, float64(value), timeSeries[0].Samples[0].Value)
+	}
+}
+
+// TestEvictSumStates checks whether the state of sums that were not seen for
+// seriesStateTTL is removed.
+func TestEvictSumStates(t *testing.T) {
+	exporter := Exporter{
+		config: Config{
+			TemporalityMismatchPolicy: TemporalityMismatchPolicyConvert,
+			PushInterval:              10 * time.Second,
+		},
+	}
+	gone := metric.NewDescriptor("gone", metric.CounterKind, metric.Int64NumberKind)
+	kept := metric.NewDescriptor("kept", metric.CounterKind, metric.Int64NumberKind)
+
+	_, err := exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &gone, 1, time.Unix(0, 0), time.Unix(10, 0)),
+		newSumRecord(t, &kept, 1, time.Unix(0, 0), time.Unix(10, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictSumStates()
+	require.Len(t, exporter.sumStates, 2)
+
+	// The state of a sum is kept while it was seen within 10 push intervals.
+	_, err = exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &kept, 2, time.Unix(0, 0), time.Unix(100, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictSumStates()
+	require.Len(t, exporter.sumStates, 2)
+
+	_, err = exporter.ConvertToTimeSeries(&recordCheckpointSet{records: []export.Record{
+		newSumRecord(t, &kept, 3, time.Unix(0, 0), time.Unix(110, 0)),
+	}})
+	require.Nil(t, err)
+	exporter.evictSumStates()
+	require.Len(t, exporter.sumStates, 1)
+}
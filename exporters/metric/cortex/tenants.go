@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// ShardingConfig configures how series are spread across several tenants. Every series is
+// assigned to one of Shards tenants by a hash of its labels, so that it is always sent to
+// the same tenant. The tenant IDs are Prefix followed by a dash and the number of the
+// shard, starting at 0.
+type ShardingConfig struct {
+	Prefix string `mapstructure:"prefix"`
+	Shards int    `mapstructure:"shards"`
+}
+
+// tenant returns the tenant ID of a shard.
+func (s *ShardingConfig) tenant(shard int) string {
+	return s.Prefix + "-" + strconv.Itoa(shard)
+}
+
+// tenantShard returns the shard a series with the labels is assigned to. The labels are
+// hashed regardless of their order.
+func tenantShard(labels []*prompb.Label, shards int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(seriesKey(labels)))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// shardByTenant groups TimeSeries by the tenant they are assigned to with TenantSharding.
+// The tenants are returned in the order of their shards, and the TimeSeries of a tenant
+// keep their order.
+func (e *Exporter) shardByTenant(timeSeries []*prompb.TimeSeries) ([]string, [][]*prompb.TimeSeries) {
+	sharding := e.config.TenantSharding
+	shards := make([][]*prompb.TimeSeries, sharding.Shards)
+	for _, ts := range timeSeries {
+		shard := tenantShard(ts.Labels, sharding.Shards)
+		shards[shard] = append(shards[shard], ts)
+	}
+
+	var tenants []string
+	var groups [][]*prompb.TimeSeries
+	for shard, group := range shards {
+		if len(group) == 0 {
+			continue
+		}
+		tenants = append(tenants, sharding.tenant(shard))
+		groups = append(groups, group)
+	}
+	return tenants, groups
+}
+
+// tenantKey is the key of the tenant in the context of a request.
+type tenantKey struct{}
+
+// withTenant returns a context that makes requests sent with it carry the tenant ID in
+// their X-Scope-OrgID header instead of the one in the Config headers.
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantFromContext returns the tenant ID set with withTenant, if any.
+func tenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestTenantShard checks whether series are assigned to shards deterministically,
+// regardless of the order of their labels, and spread across all shards.
+func TestTenantShard(t *testing.T) {
+	labels := []*prompb.Label{
+		{Name: "__name__", Value: "metric_name"},
+		{Name: "instance", Value: "a"},
+	}
+	reversed := []*prompb.Label{labels[1], labels[0]}
+	require.Equal(t, tenantShard(labels, 4), tenantShard(reversed, 4))
+
+	counts := make([]int, 4)
+	for i := 0; i < 1000; i++ {
+		shard := tenantShard([]*prompb.Label{{Name: "instance", Value: strconv.Itoa(i)}}, 4)
+		require.True(t, shard >= 0 && shard < 4)
+		counts[shard]++
+	}
+	for _, count := range counts {
+		require.True(t, count > 150)
+	}
+}
+
+// TestTenantSharding checks whether every series is sent to the same tenant in every
+// push and whether the series are spread across the tenants.
+func TestTenantSharding(t *testing.T) {
+	var lock sync.Mutex
+	var tenants map[string]string
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		lock.Lock()
+		defer lock.Unlock()
+		for _, ts := range writeRequest.Timeseries {
+			tenants[seriesKey(ts.Labels)] = req.Header.Get("X-Scope-OrgID")
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:       server.URL,
+			Headers:        map[string]string{"X-Scope-OrgID": "ignored"},
+			TenantSharding: &ShardingConfig{Prefix: "team", Shards: 3},
+		},
+	}
+
+	var pushes []map[string]string
+	for i := 0; i < 2; i++ {
+		tenants = map[string]string{}
+		require.Nil(t, exporter.Export(context.Background(), getHistogramCheckpoint(t)))
+		pushes = append(pushes, tenants)
+	}
+
+	require.Equal(t, pushes[0], pushes[1])
+	used := map[string]bool{}
+	for _, tenant := range pushes[0] {
+		require.Contains(t, []string{"team-0", "team-1", "team-2"}, tenant)
+		used[tenant] = true
+	}
+	require.True(t, len(used) > 1)
+}
@@ -15,13 +15,19 @@
 package cortex
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/require"
 
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/label"
 	"go.opentelemetry.io/otel/api/metric"
 	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
 	"go.opentelemetry.io/otel/sdk/export/metric/metrictest"
 	"go.opentelemetry.io/otel/sdk/metric/aggregator/aggregatortest"
 	"go.opentelemetry.io/otel/sdk/metric/aggregator/array"
@@ -29,8 +35,93 @@ import (
 	"go.opentelemetry.io/otel/sdk/metric/aggregator/lastvalue"
 	"go.opentelemetry.io/otel/sdk/metric/aggregator/minmaxsumcount"
 	"go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
+	"go.opentelemetry.io/otel/sdk/metric/controller/pull"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
 )
 
+// newSelfMetricsController returns a pull Controller whose Provider can be used as the
+// MeterProvider of an Exporter to read the metrics the Exporter reports on itself.
+func newSelfMetricsController() *pull.Controller {
+	return pull.New(simple.NewWithExactDistribution(), export.CumulativeExporter, pull.WithCachePeriod(0))
+}
+
+// selfMetricValues collects the metrics recorded with a Controller and returns their
+// values keyed by name and encoded labels, e.g. "name{key=value}".
+func selfMetricValues(t *testing.T, controller *pull.Controller) map[string]float64 {
+	require.Nil(t, controller.Collect(context.Background()))
+
+	values := map[string]float64{}
+	err := controller.ForEach(export.CumulativeExporter, func(record export.Record) error {
+		sum, err := record.Aggregation().(aggregation.Sum).Sum()
+		if err != nil {
+			return err
+		}
+		key := record.Descriptor().Name() + "{" + record.Labels().Encoded(label.DefaultEncoder()) + "}"
+		values[key] = sum.CoerceToFloat64(record.Descriptor().NumberKind())
+		return nil
+	})
+	require.Nil(t, err)
+	return values
+}
+
+// timeSeriesValues returns the value of the first sample of every TimeSeries keyed by
+// name and, for histogram buckets, the "le" label, e.g. "name{le=100}".
+func timeSeriesValues(timeSeries []*prompb.TimeSeries) map[string]float64 {
+	values := map[string]float64{}
+	for _, ts := range timeSeries {
+		var name, le string
+		for _, label := range ts.Labels {
+			if label.Name == "__name__" {
+				name = label.Value
+			}
+			if label.Name == "le" {
+				le = "{le=" + label.Value + "}"
+			}
+		}
+		values[name+le] = ts.Samples[0].Value
+	}
+	return values
+}
+
+// recordCheckpointSet is a CheckpointSet holding a fixed list of records. Unlike the
+// metrictest CheckpointSet, it keeps the start and end times of each record.
+type recordCheckpointSet struct {
+	sync.RWMutex
+	records []export.Record
+}
+
+// ForEach calls f for each record in the order they were added.
+func (c *recordCheckpointSet) ForEach(_ export.ExportKindSelector, f func(export.Record) error) error {
+	for _, record := range c.records {
+		if err := f(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newSumRecord returns a record with a sum aggregation and the given start and end times
+func newSumRecord(t *testing.T, desc *metric.Descriptor, value int64, start, end time.Time, labels ...kv.KeyValue) export.Record {
+	agg, ckpt := metrictest.Unslice2(sum.New(2))
+	aggregatortest.CheckedUpdate(t, agg, metric.NewInt64Number(value), desc)
+	require.NoError(t, agg.SynchronizedMove(ckpt, desc))
+
+	labelSet := label.NewSet(labels...)
+	return export.NewRecord(desc, &labelSet, testResource, ckpt.Aggregation(), start, end)
+}
+
+// newHistogramRecord returns a record with a histogram aggregation of the given values
+func newHistogramRecord(t *testing.T, desc *metric.Descriptor, boundaries []float64, end time.Time, values ...float64) export.Record {
+	agg, ckpt := metrictest.Unslice2(histogram.New(2, desc, boundaries))
+	for _, value := range values {
+		aggregatortest.CheckedUpdate(t, agg, metric.NewFloat64Number(value), desc)
+	}
+	require.NoError(t, agg.SynchronizedMove(ckpt, desc))
+
+	labelSet := label.NewSet()
+	return export.NewRecord(desc, &labelSet, testResource, ckpt.Aggregation(), time.Time{}, end)
+}
+
 // getValidCheckpointSet returns a valid checkpointset with several records
 func getValidCheckpointSet(t *testing.T) export.CheckpointSet {
 	return getSumCheckpoint(t, 321)
@@ -102,9 +193,13 @@ func getDistributionCheckpoint(t *testing.T) export.CheckpointSet {
 
 // getHistogramCheckpoint returns a checkpoint set with a histogram aggregation record
 func getHistogramCheckpoint(t *testing.T) export.CheckpointSet {
+	return getHistogramCheckpointOfKind(t, metric.ValueRecorderKind)
+}
+
+func getHistogramCheckpointOfKind(t *testing.T, kind metric.Kind) export.CheckpointSet {
 	// Create checkpoint set with resource and descriptor
 	checkpointSet := metrictest.NewCheckpointSet(testResource)
-	desc := metric.NewDescriptor("metric_name", metric.ValueRecorderKind, metric.Float64NumberKind)
+	desc := metric.NewDescriptor("metric_name", kind, metric.Float64NumberKind)
 
 	// Create aggregation, add value, and update checkpointset
 	boundaries := []float64{100, 500, 900}
@@ -475,7 +570,7 @@ var wantHistogramCheckpointSet = []*prompb.TimeSeries{
 			},
 			{
 				Name:  "le",
-				Value: "+inf",
+				Value: "+Inf",
 			},
 		},
 		Samples: []prompb.Sample{{
@@ -33,7 +33,7 @@ tls_config:
   cert_file: certfile
   key_file: keyfile
   server_name: server
-  insecure_skip_verify: true
+  insecure_skip_verify: false
 headers:
   test: header 
 `)
@@ -50,7 +50,7 @@ tls_config:
   cert_file: certfile
   key_file: keyfile
   server_name: server
-  insecure_skip_verify: true
+  insecure_skip_verify: false
 headers:
   test: header
 `)
@@ -67,7 +67,7 @@ tls_config:
   cert_file: certfile
   key_file: keyfile
   server_name: server
-  insecure_skip_verify: true
+  insecure_skip_verify: false
 headers:
   test: header
 `)
@@ -86,7 +86,7 @@ tls_config:
   cert_file: certfile
   key_file: keyfile
   server_name: server
-  insecure_skip_verify: true
+  insecure_skip_verify: false
 headers:
   test: header
 `)
@@ -103,7 +103,7 @@ tls_config:
   cert_file: certfile
   key_file: keyfile
   server_name: server
-  insecure_skip_verify: true
+  insecure_skip_verify: false
 headers:
   test: header
 `)
@@ -124,7 +124,7 @@ var validConfig = cortex.Config{
 		"cert_file":            "certfile",
 		"key_file":             "keyfile",
 		"server_name":          "server",
-		"insecure_skip_verify": "1",
+		"insecure_skip_verify": "0",
 	},
 	ProxyURL:     "",
 	PushInterval: 5 * time.Second,
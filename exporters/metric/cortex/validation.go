@@ -0,0 +1,35 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+// ValidationFailureMode applies to the series validated by MaxSeriesBytes, and sets the
+// default of MetricNameSchemaPolicy. Other checks, such as TimestampMonotonicPolicy,
+// LabelNameLengthPolicy, HistogramBucketPolicy, and SanitizationCollisionPolicy, are
+// configured by their own policies only.
+const (
+	// ValidationFailureModeOpen drops series that fail validation and sends the rest of
+	// the push, so that a few bad series do not block all others.
+	ValidationFailureModeOpen = "open"
+
+	// ValidationFailureModeClosed fails a push that contains a series that fails
+	// validation, so that nothing is sent until the series are fixed.
+	ValidationFailureModeClosed = "closed"
+)
+
+// failClosed reports whether a push with a series that is larger than MaxSeriesBytes
+// fails, according to ValidationFailureMode.
+func (e *Exporter) failClosed() bool {
+	return e.config.ValidationFailureMode == ValidationFailureModeClosed
+}
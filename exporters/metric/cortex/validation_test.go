@@ -0,0 +1,111 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/metric"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// TestValidationFailureMode checks whether a push with valid and invalid series sends
+// the valid ones in the open mode and fails without sending anything in the closed mode.
+func TestValidationFailureMode(t *testing.T) {
+	tests := []struct {
+		testName      string
+		mode          string
+		schema        string
+		wantNames     []string
+		expectedError error
+	}{
+		{
+			testName:  "Default mode drops invalid series",
+			mode:      "",
+			schema:    "app_.*",
+			wantNames: []string{"app_requests"},
+		},
+		{
+			testName:  "Open mode drops invalid series",
+			mode:      ValidationFailureModeOpen,
+			schema:    "app_.*",
+			wantNames: []string{"app_requests"},
+		},
+		{
+			testName:      "Closed mode fails on metric name",
+			mode:          ValidationFailureModeClosed,
+			schema:        "app_.*",
+			expectedError: ErrNonConformingMetricName,
+		},
+		{
+			testName:      "Closed mode fails on series size",
+			mode:          ValidationFailureModeClosed,
+			expectedError: ErrSeriesTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var names []string
+			handler := func(rw http.ResponseWriter, req *http.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				require.Nil(t, err)
+				writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+				require.Nil(t, err)
+				for _, ts := range writeRequest.Timeseries {
+					names = append(names, metricName(ts))
+				}
+			}
+			server := httptest.NewServer(http.HandlerFunc(handler))
+			defer server.Close()
+
+			config := Config{
+				Endpoint:              server.URL,
+				MetricNameSchema:      test.schema,
+				MaxSeriesBytes:        200,
+				ValidationFailureMode: test.mode,
+			}
+			require.Nil(t, config.Validate())
+			exporter := Exporter{config: config}
+
+			requests := metric.NewDescriptor("app.requests", metric.CounterKind, metric.Int64NumberKind)
+			other := metric.NewDescriptor("other.requests", metric.CounterKind, metric.Int64NumberKind)
+			large := metric.NewDescriptor("app.large", metric.CounterKind, metric.Int64NumberKind)
+			checkpointSet := &recordCheckpointSet{
+				records: []export.Record{
+					newSumRecord(t, &requests, 1, time.Time{}, time.Time{}),
+					newSumRecord(t, &other, 1, time.Time{}, time.Time{}),
+					newSumRecord(t, &large, 1, time.Time{}, time.Time{}, kv.String("query", strings.Repeat("x", 300))),
+				},
+			}
+
+			err := exporter.Export(context.Background(), checkpointSet)
+			require.Equal(t, test.expectedError, err)
+			sort.Strings(names)
+			require.Equal(t, test.wantNames, names)
+		})
+	}
+}
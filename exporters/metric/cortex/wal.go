@@ -0,0 +1,229 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Suffixes of the WAL segment files. Segments are written with walPendingSuffix and
+// renamed to walAckedSuffix once their TimeSeries were pushed successfully.
+const (
+	walPendingSuffix = ".wal"
+	walAckedSuffix   = ".ack"
+)
+
+// defaultWALMaxSegments is the number of pending segments kept when MaxSegments is not
+// set, which holds almost three hours of pushes at the default PushInterval.
+const defaultWALMaxSegments = 1000
+
+// WALConfig configures the write-ahead log. When it is enabled, the TimeSeries of every
+// push are written to a segment file in Directory before they are sent, so that they
+// survive a crash or restart of the process. Segments that were not acknowledged by a
+// successful push are sent when the export pipeline is created and before every push.
+// Acknowledged segments are deleted every TruncateFrequency. At most MaxSegments pending
+// segments are kept; the oldest ones are dropped when there are more.
+type WALConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Directory         string        `mapstructure:"directory"`
+	TruncateFrequency time.Duration `mapstructure:"truncate_frequency"`
+	MaxSegments       int           `mapstructure:"max_segments"`
+}
+
+// maxSegments returns the maximum number of pending segments.
+func (w *WALConfig) maxSegments() int {
+	if w.MaxSegments > 0 {
+		return w.MaxSegments
+	}
+	return defaultWALMaxSegments
+}
+
+// walEnabled reports whether the write-ahead log is enabled.
+func (e *Exporter) walEnabled() bool {
+	return e.config.WALConfig != nil && e.config.WALConfig.Enabled
+}
+
+// appendWAL writes TimeSeries to a new pending segment and returns its path. The segment
+// holds a Snappy-compressed WriteRequest, like the files ReplayFile reads. It is written
+// to a temporary file first, so that a crash never leaves a partial segment behind.
+func (e *Exporter) appendWAL(timeSeries []*prompb.TimeSeries) (string, error) {
+	message, err := proto.Marshal(&prompb.WriteRequest{Timeseries: timeSeries})
+	if err != nil {
+		return "", err
+	}
+
+	// Segments are named by time so that they are replayed in the order they were
+	// written.
+	e.lock.Lock()
+	id := time.Now().UnixNano()
+	if id <= e.walLastSegment {
+		id = e.walLastSegment + 1
+	}
+	e.walLastSegment = id
+	e.lock.Unlock()
+
+	directory := e.config.WALConfig.Directory
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(directory, fmt.Sprintf("%020d%s", id, walPendingSuffix))
+	file, err := ioutil.TempFile(directory, "segment")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(snappy.Encode(nil, message)); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+	return path, e.limitWAL()
+}
+
+// limitWAL drops the oldest pending segments while there are more than MaxSegments, and
+// counts their samples as dropped. It is called with walLock held.
+func (e *Exporter) limitWAL() error {
+	paths, err := filepath.Glob(filepath.Join(e.config.WALConfig.Directory, "*"+walPendingSuffix))
+	if err != nil {
+		return err
+	}
+	excess := len(paths) - e.config.WALConfig.maxSegments()
+	if excess <= 0 {
+		return nil
+	}
+	sort.Strings(paths)
+
+	samples := 0
+	for _, path := range paths[:excess] {
+		samples += segmentSamples(path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	e.logf("WAL holds more than %d pending segments, dropped %d samples of the %d oldest", e.config.WALConfig.maxSegments(), samples, excess)
+	e.addDroppedSamples(samples, "wal_full")
+	return nil
+}
+
+// replayPendingWAL sends the pending segments an earlier process left behind, so that
+// they do not wait for the first push. It is bounded by RemoteTimeout. A failed replay is
+// only logged since the segments are sent again before the first push.
+func (e *Exporter) replayPendingWAL() {
+	if !e.walEnabled() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.RemoteTimeout)
+	defer cancel()
+
+	e.walLock.Lock()
+	defer e.walLock.Unlock()
+	if err := e.replayWAL(ctx); err != nil {
+		e.logf("Could not replay the WAL: %v", err)
+	}
+}
+
+// ackWAL marks a pending segment as acknowledged, so that it is not sent again.
+func ackWAL(path string) error {
+	return os.Rename(path, strings.TrimSuffix(path, walPendingSuffix)+walAckedSuffix)
+}
+
+// replayWAL sends the TimeSeries of all pending segments, oldest first, and acknowledges
+// the segments that were sent. Segments that Cortex rejects with a 4xx status code other
+// than 429 would be rejected on every attempt, so they are logged and acknowledged as
+// well. It stops at the first segment that fails otherwise, which is sent again before the
+// next push. It is called with walLock held, so that no segment is sent twice at once.
+func (e *Exporter) replayWAL(ctx context.Context) error {
+	paths, err := filepath.Glob(filepath.Join(e.config.WALConfig.Directory, "*"+walPendingSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		compressed, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var writeRequest prompb.WriteRequest
+		message, err := snappy.Decode(nil, compressed)
+		if err == nil {
+			err = proto.Unmarshal(message, &writeRequest)
+		}
+		if err != nil {
+			e.logf("WAL segment %s is corrupt and is skipped: %v", path, err)
+			if err := ackWAL(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var result ExportResult
+		err = e.sendTimeSeries(ctx, writeRequest.Timeseries, &result)
+		if err != nil && (result.StatusCode/100 != 4 || result.StatusCode == http.StatusTooManyRequests) {
+			return err
+		}
+		if err != nil {
+			e.logf("WAL segment %s was rejected and is skipped: %v", path, err)
+		}
+		if err := ackWAL(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncateWAL deletes all acknowledged segments if TruncateFrequency has passed since they
+// were last deleted.
+func (e *Exporter) truncateWAL(now time.Time) error {
+	e.lock.Lock()
+	if now.Sub(e.walTruncated) < e.config.WALConfig.TruncateFrequency {
+		e.lock.Unlock()
+		return nil
+	}
+	e.walTruncated = now
+	e.lock.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(e.config.WALConfig.Directory, "*"+walAckedSuffix))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
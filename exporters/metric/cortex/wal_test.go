@@ -0,0 +1,242 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/contrib/exporters/metric/cortex/cortextest"
+)
+
+// walFiles returns the paths of the files in the WAL directory with the suffix.
+func walFiles(t *testing.T, dir, suffix string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	require.Nil(t, err)
+	return paths
+}
+
+// TestWALAppend checks whether the TimeSeries of a push are written to a pending segment
+// before they are sent and whether the segment is acknowledged after the push succeeded.
+func TestWALAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var pendingDuringRequest []string
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		pendingDuringRequest = walFiles(t, dir, walPendingSuffix)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:  server.URL,
+			WALConfig: &WALConfig{Enabled: true, Directory: dir, TruncateFrequency: time.Hour},
+		},
+	}
+	// The first push truncates the WAL, so acknowledged segments stay after the second.
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 2)))
+
+	require.Len(t, pendingDuringRequest, 1)
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+	require.Len(t, walFiles(t, dir, walAckedSuffix), 1)
+}
+
+// TestWALReplay checks whether segments of pushes that failed are sent before the first
+// push of a new Exporter, as after a restart.
+func TestWALReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	failing := true
+	var values []float64
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		if failing {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		for _, ts := range writeRequest.Timeseries {
+			values = append(values, ts.Samples[0].Value)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	config := Config{
+		Endpoint:  server.URL,
+		WALConfig: &WALConfig{Enabled: true, Directory: dir},
+	}
+	require.Nil(t, config.Validate())
+
+	exporter := Exporter{config: config}
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 2)))
+	require.Len(t, walFiles(t, dir, walPendingSuffix), 2)
+
+	failing = false
+	restarted := Exporter{config: config}
+	require.Nil(t, restarted.Export(context.Background(), getSumCheckpoint(t, 3)))
+	require.Equal(t, []float64{1, 2, 3}, values)
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+	require.Empty(t, walFiles(t, dir, walAckedSuffix))
+}
+
+// TestWALReplayRejected checks whether segments that Cortex rejects are skipped instead
+// of being sent before every push.
+func TestWALReplayRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Cortex rejects the sample with the value 1.
+	var values []float64
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		value := writeRequest.Timeseries[0].Samples[0].Value
+		values = append(values, value)
+		if value == 1 {
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:  server.URL,
+			WALConfig: &WALConfig{Enabled: true, Directory: dir},
+		},
+	}
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Len(t, walFiles(t, dir, walPendingSuffix), 1)
+
+	// The rejected segment is sent once more and then skipped.
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 2)))
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 3)))
+	require.Equal(t, []float64{1, 1, 2, 3}, values)
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+}
+
+// TestWALReplayFailed checks whether a push is only written to the WAL while older
+// segments cannot be sent, so that Cortex receives the samples in order.
+func TestWALReplayFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	failing := true
+	var values []float64
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.Nil(t, err)
+		writeRequest, err := cortextest.DecodeWriteRequest(body, req.Header.Get("Content-Encoding"))
+		require.Nil(t, err)
+		values = append(values, writeRequest.Timeseries[0].Samples[0].Value)
+		if failing {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := Exporter{
+		config: Config{
+			Endpoint:  server.URL,
+			WALConfig: &WALConfig{Enabled: true, Directory: dir, TruncateFrequency: time.Hour},
+		},
+	}
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 1)))
+	require.Error(t, exporter.Export(context.Background(), getSumCheckpoint(t, 2)))
+	require.Equal(t, []float64{1, 1}, values)
+	require.Len(t, walFiles(t, dir, walPendingSuffix), 2)
+
+	failing = false
+	require.Nil(t, exporter.Export(context.Background(), getSumCheckpoint(t, 3)))
+	require.Equal(t, []float64{1, 1, 1, 2, 3}, values)
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+}
+
+// TestWALReplayOnStart checks whether segments left by an earlier process are sent when
+// the export pipeline is created, before the first push.
+func TestWALReplayOnStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	config := Config{
+		Endpoint:  server.URL,
+		WALConfig: &WALConfig{Enabled: true, Directory: dir},
+	}
+	earlier := Exporter{config: config}
+	_, err = earlier.appendWAL(makeTimeSeries(1))
+	require.Nil(t, err)
+
+	controller, err := NewController(config)
+	require.Nil(t, err)
+	require.Equal(t, 1, requests)
+	require.Empty(t, walFiles(t, dir, walPendingSuffix))
+	require.Nil(t, controller.Stop(context.Background()))
+}
+
+// TestWALMaxSegments checks whether the oldest pending segments are dropped and counted
+// when there are more than MaxSegments.
+func TestWALMaxSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	controller := newSelfMetricsController()
+	exporter := Exporter{
+		config: Config{
+			MeterProvider: controller.Provider(),
+			WALConfig:     &WALConfig{Enabled: true, Directory: dir, MaxSegments: 2},
+		},
+	}
+	var segments []string
+	for _, n := range []int{1, 2, 3, 4} {
+		segment, err := exporter.appendWAL(makeTimeSeries(n))
+		require.Nil(t, err)
+		segments = append(segments, segment)
+	}
+
+	require.Equal(t, segments[2:], walFiles(t, dir, walPendingSuffix))
+	require.Equal(t, float64(3), selfMetricValues(t, controller)["cortex_exporter_dropped_samples_total{reason=wal_full}"])
+}
@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cortex
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// maxWarmUpTimeout bounds how long creating an Exporter waits for the warm-up request.
+const maxWarmUpTimeout = 5 * time.Second
+
+// warmUpTimeout returns how long the warm-up request may take, which is a tenth of
+// RemoteTimeout and at most maxWarmUpTimeout. A slow endpoint only delays creating the
+// Exporter by that much, since the first push connects to it as well.
+func (e *Exporter) warmUpTimeout() time.Duration {
+	timeout := e.config.RemoteTimeout / 10
+	if timeout <= 0 || timeout > maxWarmUpTimeout {
+		return maxWarmUpTimeout
+	}
+	return timeout
+}
+
+// warmUp establishes a connection to the endpoint with a HEAD request, so that the first
+// push does not have to wait for the connection and TLS handshake. The connection is
+// kept for the first push unless ReuseConnections is disabled. The request, including
+// fetching an OAuth2 token for it, is bounded by ctx.
+func (e *Exporter) warmUp(ctx context.Context) error {
+	client, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	requestURL, err := e.requestURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	if err := e.addHeaders(req); err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// The body is read to the end so that the connection can be reused.
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	return err
+}